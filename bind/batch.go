@@ -0,0 +1,192 @@
+package bind
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxBatchSize is the maximum number of changes sent in a single
+// UPDATE message.  Batches are sent early, when reaching the limit.
+const maxBatchSize = 100
+
+// batcher coalesces the changes to the same zone, which are made
+// within a short window, into a single UPDATE message.  This reduces
+// the number of serial bumps of the zone, and in turn the load on its
+// secondaries.
+type batcher struct {
+	mu      sync.Mutex
+	pending map[string]*batch
+
+	// scheduler bounds and orders the batches being sent, if set
+	scheduler *scheduler
+}
+
+// batch is a set of pending changes to a zone.
+type batch struct {
+	// client sends the changes of the batch
+	client *rfc2136Client
+
+	// zone is the zone being changed
+	zone string
+
+	// changes are the pending changes
+	changes []change
+
+	// waiters receive the results of the changes
+	waiters []chan batchResult
+
+	// zoneLimit is the maximum number of updates in flight for
+	// the zone
+	zoneLimit int
+
+	// task is the scheduled send of the batch, once it is closed
+	// for new changes
+	task *task
+
+	// timer sends the batch, once the window has passed
+	timer *time.Timer
+}
+
+// batchResult is the result of a single change in a batch.
+type batchResult struct {
+	result Result
+	err    error
+}
+
+// newBatcher creates a new batcher, whose batches are scheduled by
+// the given scheduler, if any.  Each batch takes a single slot of the
+// scheduler while it is being sent, and the batches of the same
+// client and zone are sent in order.
+func newBatcher(s *scheduler) *batcher {
+	b := &batcher{
+		pending:   make(map[string]*batch),
+		scheduler: s,
+	}
+
+	return b
+}
+
+// submit adds the change to the pending batch of its zone, and waits
+// for the batch to be sent.  A new batch is started if needed, which
+// is sent after the given window.  The zoneLimit is the maximum
+// number of updates in flight for the zone.
+func (b *batcher) submit(ctx context.Context, client *rfc2136Client, window time.Duration, zoneLimit int, ch change) (Result, error) {
+	key := client.batchKey(ch.Rec.Zone)
+	done := make(chan batchResult, 1)
+
+	b.mu.Lock()
+	bt, ok := b.pending[key]
+	if !ok {
+		bt = &batch{client: client, zone: ch.Rec.Zone, zoneLimit: zoneLimit}
+		bt.timer = time.AfterFunc(window, func() { b.flush(key, bt) })
+		b.pending[key] = bt
+	}
+	bt.changes = append(bt.changes, ch)
+	bt.waiters = append(bt.waiters, done)
+	full := len(bt.changes) >= maxBatchSize
+	if full {
+		b.closeLocked(key, bt)
+	}
+	b.mu.Unlock()
+
+	if full && bt.timer.Stop() {
+		go b.flush(key, bt)
+	}
+
+	select {
+	case r := <-done:
+		return r.result, r.err
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	}
+}
+
+// closeLocked closes the batch for new changes, and schedules it to
+// be sent after the previous batches with the same key.  It must be
+// called with the lock held.
+func (b *batcher) closeLocked(key string, bt *batch) {
+	delete(b.pending, key)
+	if b.scheduler != nil {
+		bt.task = b.scheduler.enqueue(bt.zone, key, bt.zoneLimit)
+	}
+}
+
+// flush sends the batch and delivers the results to the waiters.
+func (b *batcher) flush(key string, bt *batch) {
+	b.mu.Lock()
+	if b.pending[key] == bt {
+		b.closeLocked(key, bt)
+	}
+	b.mu.Unlock()
+
+	release := func() {}
+	if bt.task != nil {
+		// The send of the batch cannot be cancelled, so the wait
+		// cannot fail
+		release, _ = b.scheduler.wait(context.Background(), bt.zone, bt.task)
+	}
+
+	results, err := bt.client.apply(context.Background(), bt.zone, bt.changes)
+	release()
+
+	for i, done := range bt.waiters {
+		if err != nil {
+			done <- batchResult{err: err}
+			continue
+		}
+		done <- batchResult{result: results[i]}
+	}
+}
+
+// batchedUpdater is an Updater, which batches the changes of the
+// client.  The batches are scheduled by the batcher, when they are
+// sent.
+type batchedUpdater struct {
+	batcher   *batcher
+	client    *rfc2136Client
+	window    time.Duration
+	zoneLimit int
+}
+
+// AddTXT implements the Updater interface
+func (u *batchedUpdater) AddTXT(ctx context.Context, rec ChallengeRecord) (Result, error) {
+	return u.batcher.submit(ctx, u.client, u.window, u.zoneLimit, change{Add: true, Rec: rec})
+}
+
+// RemoveTXT implements the Updater interface
+func (u *batchedUpdater) RemoveTXT(ctx context.Context, rec ChallengeRecord) (Result, error) {
+	return u.batcher.submit(ctx, u.client, u.window, u.zoneLimit, change{Add: false, Rec: rec})
+}
+
+// batchKey returns the key of the batches of the client for the
+// given zone.  Only changes made with the same key, to the same
+// nameserver and over the same transport are batched together, i.e.
+// changes, whose clients differ in any setting affecting how the
+// update is sent, are never sent by the client of another change.
+func (c *rfc2136Client) batchKey(zone string) string {
+	h := sha256.New()
+	if c.key != nil {
+		fmt.Fprintf(h, "tsig:%s:%s:%s:%d\n", c.key.Name, c.key.Algorithm, c.key.Secret, c.key.Fudge)
+	}
+	if c.transport.SIG0 != nil {
+		fmt.Fprintf(h, "sig0:%s\n", c.transport.SIG0.Key.String())
+	}
+	if c.transport.TLSConfig != nil {
+		fmt.Fprintf(h, "tls:%s:%s\n", c.transport.TLSConfig.ServerName, c.tlsFingerprint)
+	}
+	if c.resolver != nil {
+		fmt.Fprintf(h, "resolvers:%s:%s\n", c.resolver.URL, c.resolver.Servers)
+	}
+	fmt.Fprintf(h, "%s:%s:%s:%s:%s:%s:%s\n", c.transport.Net, c.transport.SourceAddr, c.nameservers, c.discovery, c.failover, c.serverTimeout, c.lease)
+	fmt.Fprintf(h, "timeouts:%s:%s:%s\n", c.transport.DialTimeout, c.transport.ReadTimeout, c.transport.WriteTimeout)
+	fmt.Fprintf(h, "retry:%d:%s\n", c.retry.Attempts, c.retry.Backoff)
+	for _, server := range c.notifyServers {
+		fmt.Fprintf(h, "notify:%s\n", server)
+	}
+
+	return fmt.Sprintf("%s/%s", zone, hex.EncodeToString(h.Sum(nil)))
+}
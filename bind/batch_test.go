@@ -0,0 +1,315 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// submitAll submits the changes concurrently and returns their
+// results and errors.
+func submitAll(b *batcher, client *rfc2136Client, window time.Duration, changes []change) ([]Result, []error) {
+	results := make([]Result, len(changes))
+	errs := make([]error, len(changes))
+
+	var wg sync.WaitGroup
+	for i, ch := range changes {
+		wg.Add(1)
+		go func(i int, ch change) {
+			defer wg.Done()
+			results[i], errs[i] = b.submit(context.Background(), client, window, 4, ch)
+		}(i, ch)
+	}
+	wg.Wait()
+
+	return results, errs
+}
+
+func TestBatcher(t *testing.T) {
+	ts := newTestServer(t)
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	client := newRFC2136Client(key)
+	client.nameservers = []string{ts.Addr}
+
+	fqdn := "_acme-challenge.example.com."
+	changes := make([]change, 0)
+	want := make([]string, 0)
+	for i := 0; i < 40; i++ {
+		value := fmt.Sprintf("token-%02d", i)
+		rec := ChallengeRecord{Zone: "example.com.", FQDN: fqdn, TTL: 300, Value: value}
+		changes = append(changes, change{Add: true, Rec: rec})
+		want = append(want, value)
+	}
+
+	results, errs := submitAll(newBatcher(nil), client, 100*time.Millisecond, changes)
+	for i := range changes {
+		if errs[i] != nil {
+			t.Fatalf("%d: failed to add record: %s", i, errs[i])
+		}
+
+		if !results[i].Changed || results[i].Server != ts.Addr {
+			t.Errorf("%d: want change by %s, got %+v", i, ts.Addr, results[i])
+		}
+	}
+
+	if ts.Updates() != 1 {
+		t.Errorf("want 1 update, got %d", ts.Updates())
+	}
+
+	got := ts.TXT(fqdn)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestBatcherMixed(t *testing.T) {
+	ts := newTestServer(t)
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	client := newRFC2136Client(key)
+	client.nameservers = []string{ts.Addr}
+
+	fqdn := "_acme-challenge.example.com."
+	rec1 := ChallengeRecord{Zone: "example.com.", FQDN: fqdn, TTL: 300, Value: "token-1"}
+	rec2 := ChallengeRecord{Zone: "example.com.", FQDN: fqdn, TTL: 300, Value: "token-2"}
+	rec3 := ChallengeRecord{Zone: "example.com.", FQDN: fqdn, TTL: 300, Value: "token-3"}
+	if _, err := client.AddTXT(context.Background(), rec1); err != nil {
+		t.Fatalf("failed to add record: %s", err)
+	}
+
+	changes := []change{
+		{Add: false, Rec: rec1},
+		{Add: true, Rec: rec2},
+		{Add: false, Rec: rec3},
+	}
+
+	results, errs := submitAll(newBatcher(nil), client, 100*time.Millisecond, changes)
+	for i, wantChanged := range []bool{true, true, false} {
+		if errs[i] != nil {
+			t.Fatalf("%d: failed to apply change: %s", i, errs[i])
+		}
+
+		if results[i].Changed != wantChanged {
+			t.Errorf("%d: want changed %t, got %t", i, wantChanged, results[i].Changed)
+		}
+	}
+
+	if ts.Updates() != 2 {
+		t.Errorf("want 2 updates, got %d", ts.Updates())
+	}
+
+	if got := ts.TXT(fqdn); !slices.Equal(got, []string{"token-2"}) {
+		t.Errorf("want [token-2], got %v", got)
+	}
+}
+
+func TestBatcherZones(t *testing.T) {
+	ts := newTestServer(t)
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	client := newRFC2136Client(key)
+	client.nameservers = []string{ts.Addr}
+
+	changes := []change{
+		{Add: true, Rec: ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}},
+		{Add: true, Rec: ChallengeRecord{Zone: "example.org.", FQDN: "_acme-challenge.example.org.", TTL: 300, Value: "token-2"}},
+		{Add: true, Rec: ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.www.example.com.", TTL: 300, Value: "token-3"}},
+	}
+
+	_, errs := submitAll(newBatcher(nil), client, 100*time.Millisecond, changes)
+	if err := errors.Join(errs...); err != nil {
+		t.Fatalf("failed to add records: %s", err)
+	}
+
+	if ts.Updates() != 2 {
+		t.Errorf("want 2 updates, got %d", ts.Updates())
+	}
+}
+
+func TestBatcherError(t *testing.T) {
+	ts := newTestServer(t)
+	ts.Respond(dns.RcodeRefused)
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	client := newRFC2136Client(key)
+	client.nameservers = []string{ts.Addr}
+
+	changes := []change{
+		{Add: true, Rec: ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}},
+		{Add: true, Rec: ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-2"}},
+	}
+
+	_, errs := submitAll(newBatcher(nil), client, 100*time.Millisecond, changes)
+	for i, err := range errs {
+		if !errors.Is(err, ErrRefused) {
+			t.Errorf("%d: want ErrRefused, got %v", i, err)
+		}
+	}
+}
+
+func TestBatcherFull(t *testing.T) {
+	ts := newTestServer(t)
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	client := newRFC2136Client(key)
+	client.nameservers = []string{ts.Addr}
+
+	changes := make([]change, 0)
+	for i := 0; i < maxBatchSize; i++ {
+		rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: fmt.Sprintf("token-%d", i)}
+		changes = append(changes, change{Add: true, Rec: rec})
+	}
+
+	// The batch is sent when full, without waiting for the window
+	start := time.Now()
+	_, errs := submitAll(newBatcher(nil), client, time.Hour, changes)
+	if err := errors.Join(errs...); err != nil {
+		t.Fatalf("failed to add records: %s", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Minute {
+		t.Errorf("want batch sent when full, got it after %s", elapsed)
+	}
+
+	if ts.Updates() != 1 {
+		t.Errorf("want 1 update, got %d", ts.Updates())
+	}
+}
+
+func TestBatcherScheduled(t *testing.T) {
+	ts := newTestServer(t)
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	client := newRFC2136Client(key)
+	client.nameservers = []string{ts.Addr}
+
+	// The changes of the same name and more changes than the limit
+	// of the zone are sent in a single batch
+	changes := make([]change, 0)
+	for _, fqdn := range []string{"_acme-challenge.example.com.", "_acme-challenge.www.example.com."} {
+		for i := 0; i < 10; i++ {
+			rec := ChallengeRecord{Zone: "example.com.", FQDN: fqdn, TTL: 300, Value: fmt.Sprintf("token-%d", i)}
+			changes = append(changes, change{Add: true, Rec: rec})
+		}
+	}
+
+	// Hold the only slot, so that the batch must wait to be sent
+	s := newScheduler(1)
+	release, err := s.acquire(context.Background(), "example.org.", "_acme-challenge.example.org.", 4)
+	if err != nil {
+		t.Fatalf("failed to acquire: %s", err)
+	}
+
+	done := make(chan []error)
+	go func() {
+		_, errs := submitAll(newBatcher(s), client, 10*time.Millisecond, changes)
+		done <- errs
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	if ts.Updates() != 0 {
+		t.Fatalf("want batch waiting for a slot, got %d updates", ts.Updates())
+	}
+
+	release()
+	if err := errors.Join(<-done...); err != nil {
+		t.Fatalf("failed to add records: %s", err)
+	}
+
+	if ts.Updates() != 1 {
+		t.Errorf("want 1 update, got %d", ts.Updates())
+	}
+
+	if s.running != 0 || len(s.zones) != 0 {
+		t.Errorf("want no updates left, got %d running in %d zones", s.running, len(s.zones))
+	}
+}
+
+func TestBatcherTLSConfigs(t *testing.T) {
+	ts, certPEM, keyPEM := newTestTLSServer(t)
+	otherCA, _ := newTestCertificate(t)
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	// The issuers only differ in the CA bundle, which the client
+	// of the second one does not trust the server with
+	newClient := func(caBundle []byte) *rfc2136Client {
+		cfg := &BindProviderConfig{
+			Transport:      TransportTLS,
+			Nameservers:    []string{ts.Addr},
+			TLSServerName:  "ns1.example.com",
+			UpdateAttempts: 1,
+			tsig:           key,
+			tlsCA:          caBundle,
+			tlsClientCert:  certPEM,
+			tlsClientKey:   keyPEM,
+		}
+
+		client, err := cfg.newRFC2136Client()
+		if err != nil {
+			t.Fatalf("failed to create client: %s", err)
+		}
+
+		return client
+	}
+	trusted := newClient(certPEM)
+	untrusted := newClient(otherCA)
+
+	if trusted.batchKey("example.com.") == untrusted.batchKey("example.com.") {
+		t.Fatalf("want different batch keys for different CA bundles")
+	}
+
+	b := newBatcher(nil)
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i, client := range []*rfc2136Client{trusted, untrusted} {
+		wg.Add(1)
+		go func(i int, client *rfc2136Client) {
+			defer wg.Done()
+
+			rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: fmt.Sprintf("token-%d", i)}
+			_, errs[i] = b.submit(context.Background(), client, 100*time.Millisecond, 4, change{Add: true, Rec: rec})
+		}(i, client)
+	}
+	wg.Wait()
+
+	if errs[0] != nil {
+		t.Errorf("want change of the trusted client applied, got %s", errs[0])
+	}
+
+	if errs[1] == nil {
+		t.Errorf("want change of the untrusted client rejected")
+	}
+
+	if got := ts.TXT("_acme-challenge.example.com."); !slices.Equal(got, []string{"token-0"}) {
+		t.Errorf("want only the trusted record, got %v", got)
+	}
+}
@@ -8,10 +8,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"net/netip"
 	"os"
-	"os/exec"
 	"slices"
-	"strconv"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -19,6 +20,7 @@ import (
 
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
 
 	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
 	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
@@ -28,9 +30,13 @@ import (
 // configured with a list of allowed zones.
 var ErrNoAllowedZonesConfigured = errors.New("no allowed zones configured")
 
-// ErrNoTSIGKeyConfigured is returned when the solver was not
-// configured with a TSIG key.
-var ErrNoTSIGKeyConfigured = errors.New("no TSIG key configured")
+// ErrNoTSIGKeyConfigured is returned when the solver was configured
+// with neither a TSIG key, nor a SIG(0) key.
+var ErrNoTSIGKeyConfigured = errors.New("no TSIG or SIG(0) key configured")
+
+// ErrConflictingKeysConfigured is returned when the solver was
+// configured with both a TSIG key and a SIG(0) key.
+var ErrConflictingKeysConfigured = errors.New("only one of TSIG and SIG(0) keys can be configured")
 
 // DefaultTTL represents the default TTL value to set for new records,
 // unless specified in the configuration
@@ -43,17 +49,92 @@ type BindProviderSolver struct {
 	// The helper script we use to create and delete the ACME
 	// Challenge TXT records.
 	AcmeHelperScript string
+
+	// Backend is the name of the backend to use, unless
+	// specified in the configuration.
+	Backend string
+
+	// SourceAddress is the local address the dynamic updates
+	// are sent from, unless specified in the configuration.
+	SourceAddress string
+
+	// MaxConcurrentUpdates is the maximum number of updates in
+	// flight across all zones.
+	MaxConcurrentUpdates int
+
+	// Resolvers are the recursive resolvers used to discover the
+	// nameservers, unless specified in the configuration.
+	Resolvers []string
+
+	// DoHURL is the URL of the DNS-over-HTTPS resolver used to
+	// discover the nameservers, unless specified in the
+	// configuration.
+	DoHURL string
+
+	// backends contains the registered backends
+	backends map[string]BackendFactory
+
+	// batcher batches the changes of the native backend
+	batcher *batcher
+
+	// scheduler bounds and orders the updates
+	scheduler *scheduler
+
+	// convergence tracks the records, which the public
+	// nameservers do not serve yet in hidden primary mode
+	convergence *convergenceTracker
 }
 
 // NewSolver creates a new BIND9 DNS-01 solver
 func NewSolver() *BindProviderSolver {
 	b := &BindProviderSolver{
-		AcmeHelperScript: "acme-challenge-helper.sh",
+		AcmeHelperScript:     "acme-challenge-helper.sh",
+		Backend:              DefaultBackend,
+		MaxConcurrentUpdates: DefaultMaxConcurrentUpdates,
+		backends:             make(map[string]BackendFactory),
+		scheduler:            newScheduler(DefaultMaxConcurrentUpdates),
+		convergence:          newConvergenceTracker(),
 	}
+	b.batcher = newBatcher(b.scheduler)
+
+	mem := NewMemoryUpdater()
+	b.RegisterBackend(BackendNative, func(cfg *BindProviderConfig) (Updater, error) {
+		client, err := cfg.newRFC2136Client()
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.BatchWindow.Duration > 0 {
+			batched := &batchedUpdater{
+				batcher:   b.batcher,
+				client:    client,
+				window:    cfg.BatchWindow.Duration,
+				zoneLimit: cfg.MaxConcurrentUpdatesPerZone,
+			}
+
+			return batched, nil
+		}
+
+		return client, nil
+	})
+	b.RegisterBackend(BackendScript, func(cfg *BindProviderConfig) (Updater, error) {
+		return &scriptUpdater{script: b.AcmeHelperScript, cfg: cfg, resolver: cfg.resolver}, nil
+	})
+	b.RegisterBackend(BackendMemory, func(cfg *BindProviderConfig) (Updater, error) {
+		return mem, nil
+	})
 
 	return b
 }
 
+// RegisterBackend registers a backend with the given name, which
+// can then be selected using the `backend` configuration field.
+// Registering a backend with the name of an already registered
+// backend replaces it.
+func (b *BindProviderSolver) RegisterBackend(name string, factory BackendFactory) {
+	b.backends[name] = factory
+}
+
 // bindProviderConfig represents the configuration for the BIND solver.
 type BindProviderConfig struct {
 	// Change the two fields below according to the format of the configuration
@@ -68,6 +149,23 @@ type BindProviderConfig struct {
 	// update the DNS records.
 	TSIGKeyRef cmmeta.SecretKeySelector `json:"tsigKeyRef"`
 
+	// TSIGAlgorithm is the expected HMAC algorithm of the TSIG
+	// key, e.g. hmac-sha256.  If specified, it must match the
+	// algorithm of the key.
+	TSIGAlgorithm string `json:"tsigAlgorithm"`
+
+	// AllowLegacyHMACMD5 allows using TSIG keys with the legacy
+	// hmac-md5 algorithm.
+	AllowLegacyHMACMD5 bool `json:"allowLegacyHMACMD5"`
+
+	// TSIGFudge is the time window, within which TSIG signatures
+	// are considered valid.
+	TSIGFudge metav1.Duration `json:"tsigFudge"`
+
+	// SIG0KeyRef is the SIG(0) key pair used to dynamically
+	// update the DNS records, as an alternative to TSIGKeyRef.
+	SIG0KeyRef *SIG0KeySelector `json:"sig0KeyRef,omitempty"`
+
 	// TTL is the time-to-live to set on the newly created TXT
 	// records
 	TTL int `json:"ttl"`
@@ -76,9 +174,146 @@ type BindProviderConfig struct {
 	// allowed to manage
 	AllowedZones []string `json:"allowedZones"`
 
+	// Backend is the name of the backend, which creates and
+	// deletes the TXT records
+	Backend string `json:"backend"`
+
+	// HookTimeout is the time after which the ACME helper script
+	// is killed
+	HookTimeout metav1.Duration `json:"hookTimeout"`
+
+	// Transport is the transport over which the dynamic updates
+	// are sent, i.e. udp, tcp, auto or tls
+	Transport string `json:"transport"`
+
+	// DialTimeout is the timeout for establishing a connection
+	// with the nameserver
+	DialTimeout metav1.Duration `json:"dialTimeout"`
+
+	// ReadTimeout is the timeout for reading a response from the
+	// nameserver
+	ReadTimeout metav1.Duration `json:"readTimeout"`
+
+	// WriteTimeout is the timeout for sending a request to the
+	// nameserver
+	WriteTimeout metav1.Duration `json:"writeTimeout"`
+
+	// TLSCARef is the optional CA bundle used to verify the
+	// certificate of the nameserver when using the tls transport
+	TLSCARef *cmmeta.SecretKeySelector `json:"tlsCARef,omitempty"`
+
+	// TLSClientCertRef is the optional client certificate
+	// presented to the nameserver when using the tls transport
+	TLSClientCertRef *cmmeta.SecretKeySelector `json:"tlsClientCertRef,omitempty"`
+
+	// TLSClientKeyRef is the private key of the client
+	// certificate
+	TLSClientKeyRef *cmmeta.SecretKeySelector `json:"tlsClientKeyRef,omitempty"`
+
+	// TLSServerName is the name used to verify the certificate
+	// of the nameserver when using the tls transport
+	TLSServerName string `json:"tlsServerName"`
+
+	// SourceAddress is the local IP address and optional port
+	// the dynamic updates are sent from, e.g. 192.0.2.1 or
+	// [2001:db8::1]:5300
+	SourceAddress string `json:"sourceAddress"`
+
+	// BatchWindow is the time during which changes to the same
+	// zone are collected and sent in a single UPDATE message.
+	// Batching is disabled, unless specified.
+	BatchWindow metav1.Duration `json:"batchWindow"`
+
+	// MaxConcurrentUpdatesPerZone is the maximum number of
+	// updates in flight for each zone.
+	MaxConcurrentUpdatesPerZone int `json:"maxConcurrentUpdatesPerZone"`
+
+	// UpdateAttempts is the maximum number of attempts made to
+	// send an update, which failed with a network error or
+	// SERVFAIL.
+	UpdateAttempts int `json:"updateAttempts"`
+
+	// RetryBackoff is the time to wait before retrying a failed
+	// update, which doubles with each retry.
+	RetryBackoff metav1.Duration `json:"retryBackoff"`
+
+	// UpdateLease is the time after which nameservers supporting
+	// the EDNS0 Update Lease option remove the TXT records on
+	// their own, in case they are never cleaned up.  Disabled,
+	// unless specified.
+	UpdateLease metav1.Duration `json:"updateLease"`
+
+	// Nameservers is the list of nameservers the updates are
+	// sent to, specified as host, host:port or [v6]:port.  If
+	// empty, the nameservers are discovered for each zone.
+	Nameservers []string `json:"nameservers"`
+
+	// Discovery is the mode in which the nameservers are
+	// discovered, if not specified, i.e. soa or srv
+	Discovery string `json:"discovery"`
+
+	// Resolvers are the recursive resolvers used to discover the
+	// nameservers, specified as host, host:port or [v6]:port.
+	// If empty, the resolvers of the system are used.
+	Resolvers []string `json:"resolvers"`
+
+	// DoHURL is the URL of the DNS-over-HTTPS resolver, which is
+	// used to discover the nameservers instead of the resolvers
+	DoHURL string `json:"dohURL"`
+
+	// Failover is the mode in which the nameservers are tried,
+	// i.e. sequential or parallel.  The parallel mode requires
+	// the nameservers to be configured.
+	Failover string `json:"failover"`
+
+	// NameserverTimeout is the time within which a nameserver
+	// must apply an update, including its retries, before the
+	// next nameserver is tried
+	NameserverTimeout metav1.Duration `json:"nameserverTimeout"`
+
+	// HiddenPrimary specifies that the nameservers are hidden
+	// primaries, which are not listed in the NS records of the
+	// zone.  Present only succeeds, once the public nameservers
+	// from the NS records serve the TXT record.
+	HiddenPrimary bool `json:"hiddenPrimary"`
+
+	// ConvergenceTimeout is the time within which the public
+	// nameservers must serve the TXT record in hidden primary
+	// mode, before the webhook stops waiting for them
+	ConvergenceTimeout metav1.Duration `json:"convergenceTimeout"`
+
+	// Notify is the list of servers, e.g. the secondaries of the
+	// zone, which are sent a NOTIFY message after each update.
+	// Servers are specified as host, host:port or [v6]:port.
+	Notify []string `json:"notify"`
+
 	// tsigKey represents the raw TSIG key after fetching it from
 	// the secret store
 	tsigKey []byte
+
+	// tsig is the parsed and validated TSIG key
+	tsig *tsigKey
+
+	// sig0PublicKey and sig0PrivateKey represent the raw SIG(0)
+	// key pair after fetching it from the secret store
+	sig0PublicKey  []byte
+	sig0PrivateKey []byte
+
+	// tlsCA, tlsClientCert and tlsClientKey represent the raw
+	// TLS materials after fetching them from the secret store
+	tlsCA         []byte
+	tlsClientCert []byte
+	tlsClientKey  []byte
+
+	// sourceAddr is the parsed source address
+	sourceAddr netip.AddrPort
+
+	// resolver is used to discover the nameservers, if the
+	// resolvers are specified
+	resolver *resolver
+
+	// notifyServers are the parsed endpoints of the notify list
+	notifyServers []endpoint
 }
 
 // dumpTSIGKey dumps the contents of the TSIG key in the given path
@@ -97,6 +332,55 @@ func (bpc *BindProviderConfig) dumpTSIGKey(path string) (*os.File, error) {
 	return tmpFile, nil
 }
 
+// TSIGKey returns the raw TSIG key, as fetched from the secret
+// store.
+func (bpc *BindProviderConfig) TSIGKey() []byte {
+	return bpc.tsigKey
+}
+
+// newRFC2136Client creates a new client for sending dynamic updates,
+// which are signed with the configured TSIG or SIG(0) key.
+func (bpc *BindProviderConfig) newRFC2136Client() (*rfc2136Client, error) {
+	var client *rfc2136Client
+	if bpc.SIG0KeyRef != nil {
+		key, err := parseSIG0Key(bpc.sig0PublicKey, bpc.sig0PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		client = newSIG0RFC2136Client(key)
+	} else {
+		client = newRFC2136Client(bpc.tsig)
+	}
+
+	client.transport.Net = bpc.Transport
+	client.transport.DialTimeout = bpc.DialTimeout.Duration
+	client.transport.ReadTimeout = bpc.ReadTimeout.Duration
+	client.transport.WriteTimeout = bpc.WriteTimeout.Duration
+	client.transport.SourceAddr = bpc.sourceAddr
+	client.retry = retryPolicy{Attempts: bpc.UpdateAttempts, Backoff: bpc.RetryBackoff.Duration}
+	client.lease = bpc.UpdateLease.Duration
+	client.notifyServers = bpc.notifyServers
+	client.nameservers = bpc.Nameservers
+	client.discovery = bpc.Discovery
+	if bpc.resolver != nil {
+		client.resolver = bpc.resolver
+		client.transport.Resolver = bpc.resolver
+	}
+	client.failover = bpc.Failover
+	client.serverTimeout = bpc.NameserverTimeout.Duration
+
+	if bpc.Transport == TransportTLS {
+		tlsConfig, err := newTLSConfig(bpc.tlsCA, bpc.tlsClientCert, bpc.tlsClientKey, bpc.TLSServerName)
+		if err != nil {
+			return nil, err
+		}
+		client.transport.TLSConfig = tlsConfig
+		client.tlsFingerprint = tlsFingerprint(bpc.tlsCA, bpc.tlsClientCert, bpc.tlsClientKey)
+	}
+
+	return client, nil
+}
+
 // Name implements the webhook.Solver interface
 func (b *BindProviderSolver) Name() string {
 	return "bind9"
@@ -116,19 +400,25 @@ func (b *BindProviderSolver) Present(ch *v1alpha1.ChallengeRequest) error {
 		return fmt.Errorf("Zone %s is not in the allowed-zones list", zoneName)
 	}
 
-	// Dump the TSIG key locally, so that we can pass it to
-	// the helper scripts. Make sure to delete it afterwards.
-	tsigFile, err := cfg.dumpTSIGKey("")
+	updater, err := b.newScheduledUpdater(&cfg)
 	if err != nil {
-		return fmt.Errorf("failed to dump TSIG key: %s", err)
+		return err
+	}
+
+	rec := newChallengeRecord(ch, cfg)
+	result, err := updater.AddTXT(context.Background(), rec)
+	if err != nil {
+		return fmt.Errorf("failed to create TXT record %s: %w", ch.ResolvedFQDN, err)
+	}
+
+	if result.Changed {
+		klog.InfoS("created TXT record", "fqdn", ch.ResolvedFQDN, "server", result.Server, "uid", ch.UID)
+	} else {
+		klog.InfoS("TXT record already exists", "fqdn", ch.ResolvedFQDN, "server", result.Server, "uid", ch.UID)
 	}
-	defer os.Remove(tsigFile.Name())
 
-	// Call our helper script here to create the respective TXT
-	// records as part of the DNS-01 challenge
-	cmd := exec.Command(b.AcmeHelperScript, "create", zoneName, ch.ResolvedFQDN, tsigFile.Name(), strconv.Itoa(cfg.TTL), ch.Key)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to create TXT record %s: %s", ch.ResolvedFQDN, err)
+	if cfg.HiddenPrimary {
+		return b.waitConverged(context.Background(), &cfg, rec)
 	}
 
 	return nil
@@ -148,24 +438,76 @@ func (b *BindProviderSolver) CleanUp(ch *v1alpha1.ChallengeRequest) error {
 		return fmt.Errorf("Zone %s is not in the allowed-zones list", zoneName)
 	}
 
-	// Dump the TSIG key locally, so that we can pass it to
-	// the helper scripts. Make sure to delete it afterwards.
-	tsigFile, err := cfg.dumpTSIGKey("")
+	updater, err := b.newScheduledUpdater(&cfg)
+	if err != nil {
+		return err
+	}
+
+	rec := newChallengeRecord(ch, cfg)
+	b.convergence.done(rec)
+	result, err := updater.RemoveTXT(context.Background(), rec)
 	if err != nil {
-		return fmt.Errorf("failed to dump TSIG key: %s", err)
+		return fmt.Errorf("failed to delete TXT record %s: %w", ch.ResolvedFQDN, err)
 	}
-	defer os.Remove(tsigFile.Name())
 
-	// Call our helper script here to delete the respective TXT
-	// record
-	cmd := exec.Command(b.AcmeHelperScript, "delete", zoneName, ch.ResolvedFQDN, tsigFile.Name(), strconv.Itoa(cfg.TTL), ch.Key)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to delete TXT record %s: %s", ch.ResolvedFQDN, err)
+	if result.Changed {
+		klog.InfoS("deleted TXT record", "fqdn", ch.ResolvedFQDN, "server", result.Server, "uid", ch.UID)
+	} else {
+		klog.InfoS("TXT record already deleted", "fqdn", ch.ResolvedFQDN, "server", result.Server, "uid", ch.UID)
 	}
 
 	return nil
 }
 
+// newUpdater creates the Updater of the configured backend.
+func (b *BindProviderSolver) newUpdater(cfg *BindProviderConfig) (Updater, error) {
+	factory, ok := b.backends[cfg.Backend]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, cfg.Backend)
+	}
+
+	return factory(cfg)
+}
+
+// newScheduledUpdater creates the Updater of the configured backend,
+// whose updates are bounded and ordered by the scheduler of the
+// solver.
+func (b *BindProviderSolver) newScheduledUpdater(cfg *BindProviderConfig) (Updater, error) {
+	updater, err := b.newUpdater(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Batches take a slot of the scheduler only while being sent,
+	// rather than a slot for each of their changes
+	if _, ok := updater.(*batchedUpdater); ok {
+		return updater, nil
+	}
+
+	scheduled := &scheduledUpdater{
+		scheduler: b.scheduler,
+		updater:   updater,
+		zoneLimit: cfg.MaxConcurrentUpdatesPerZone,
+	}
+
+	return scheduled, nil
+}
+
+// newChallengeRecord creates the TXT record for the given challenge.
+func newChallengeRecord(ch *v1alpha1.ChallengeRequest, cfg BindProviderConfig) ChallengeRecord {
+	rec := ChallengeRecord{
+		Zone:              ch.ResolvedZone,
+		FQDN:              ch.ResolvedFQDN,
+		TTL:               cfg.TTL,
+		Value:             ch.Key,
+		UID:               string(ch.UID),
+		DNSName:           ch.DNSName,
+		ResourceNamespace: ch.ResourceNamespace,
+	}
+
+	return rec
+}
+
 // Initialize initializes the BIND solver
 func (b *BindProviderSolver) Initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
 	cl, err := kubernetes.NewForConfig(kubeClientConfig)
@@ -175,6 +517,11 @@ func (b *BindProviderSolver) Initialize(kubeClientConfig *rest.Config, stopCh <-
 
 	b.client = cl
 
+	if b.MaxConcurrentUpdates <= 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidConcurrency, b.MaxConcurrentUpdates)
+	}
+	b.scheduler.setLimit(b.MaxConcurrentUpdates)
+
 	return nil
 }
 
@@ -182,7 +529,19 @@ func (b *BindProviderSolver) Initialize(kubeClientConfig *rest.Config, stopCh <-
 // the typed config struct.
 func (b *BindProviderSolver) loadConfig(cfgJSON *extapi.JSON, namespace string) (BindProviderConfig, error) {
 	cfg := BindProviderConfig{
-		TTL: DefaultTTL,
+		TTL:                DefaultTTL,
+		TSIGFudge:          metav1.Duration{Duration: DefaultTSIGFudge * time.Second},
+		HookTimeout:        metav1.Duration{Duration: DefaultHookTimeout},
+		Transport:          DefaultTransport,
+		Discovery:          DefaultDiscovery,
+		Failover:           DefaultFailover,
+		NameserverTimeout:  metav1.Duration{Duration: DefaultNameserverTimeout},
+		ConvergenceTimeout: metav1.Duration{Duration: DefaultConvergenceTimeout},
+		DialTimeout:        metav1.Duration{Duration: DefaultDialTimeout},
+		ReadTimeout:        metav1.Duration{Duration: DefaultReadTimeout},
+		WriteTimeout:       metav1.Duration{Duration: DefaultWriteTimeout},
+		UpdateAttempts:     DefaultUpdateAttempts,
+		RetryBackoff:       metav1.Duration{Duration: DefaultRetryBackoff},
 	}
 
 	// We require TSIG key and allowed zones to be configured
@@ -200,29 +559,283 @@ func (b *BindProviderSolver) loadConfig(cfgJSON *extapi.JSON, namespace string)
 		cfg.TTL = DefaultTTL
 	}
 
+	if cfg.HookTimeout.Duration <= 0 {
+		cfg.HookTimeout.Duration = DefaultHookTimeout
+	}
+
+	if cfg.TSIGAlgorithm != "" {
+		algorithm, err := canonicalTSIGAlgorithm(cfg.TSIGAlgorithm, cfg.AllowLegacyHMACMD5)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.TSIGAlgorithm = algorithm
+	}
+
+	if cfg.TSIGFudge.Duration == 0 {
+		cfg.TSIGFudge.Duration = DefaultTSIGFudge * time.Second
+	}
+
+	if cfg.TSIGFudge.Duration < time.Second || cfg.TSIGFudge.Duration > math.MaxUint16*time.Second {
+		return cfg, ErrInvalidTSIGFudge
+	}
+
+	switch cfg.Transport {
+	case "":
+		cfg.Transport = DefaultTransport
+	case TransportUDP, TransportTCP, TransportAuto, TransportTLS:
+	default:
+		return cfg, fmt.Errorf("%w: %s", ErrUnknownTransport, cfg.Transport)
+	}
+
+	port := DefaultUpdatePort
+	if cfg.Transport == TransportTLS {
+		port = DefaultTLSPort
+	}
+
+	for _, ns := range cfg.Nameservers {
+		if _, err := parseEndpoint(ns, port); err != nil {
+			return cfg, fmt.Errorf("nameservers: %w", err)
+		}
+	}
+
+	switch cfg.Discovery {
+	case "":
+		cfg.Discovery = DefaultDiscovery
+	case DiscoverySOA, DiscoverySRV:
+	default:
+		return cfg, fmt.Errorf("%w: %s", ErrUnknownDiscovery, cfg.Discovery)
+	}
+
+	if cfg.Discovery == DiscoverySRV && cfg.Transport == TransportTLS {
+		return cfg, ErrSRVDiscoveryTLS
+	}
+
+	switch cfg.Failover {
+	case "":
+		cfg.Failover = DefaultFailover
+	case FailoverSequential, FailoverParallel:
+	default:
+		return cfg, fmt.Errorf("%w: %s", ErrUnknownFailover, cfg.Failover)
+	}
+
+	if cfg.Failover == FailoverParallel && len(cfg.Nameservers) == 0 {
+		return cfg, ErrNoParallelNameserversConfigured
+	}
+
+	if (cfg.TLSClientCertRef == nil) != (cfg.TLSClientKeyRef == nil) {
+		return cfg, ErrIncompleteTLSClientCertificate
+	}
+
+	if cfg.DialTimeout.Duration <= 0 {
+		cfg.DialTimeout.Duration = DefaultDialTimeout
+	}
+
+	if cfg.ReadTimeout.Duration <= 0 {
+		cfg.ReadTimeout.Duration = DefaultReadTimeout
+	}
+
+	if cfg.WriteTimeout.Duration <= 0 {
+		cfg.WriteTimeout.Duration = DefaultWriteTimeout
+	}
+
+	if cfg.UpdateAttempts <= 0 {
+		cfg.UpdateAttempts = DefaultUpdateAttempts
+	}
+
+	if cfg.RetryBackoff.Duration <= 0 {
+		cfg.RetryBackoff.Duration = DefaultRetryBackoff
+	}
+
+	if cfg.NameserverTimeout.Duration <= 0 {
+		cfg.NameserverTimeout.Duration = DefaultNameserverTimeout
+	}
+
+	if cfg.HiddenPrimary && len(cfg.Nameservers) == 0 {
+		return cfg, ErrNoHiddenPrimaryConfigured
+	}
+
+	if cfg.ConvergenceTimeout.Duration <= 0 {
+		cfg.ConvergenceTimeout.Duration = DefaultConvergenceTimeout
+	}
+
+	if lease := cfg.UpdateLease.Duration; lease != 0 && (lease < time.Second || lease > math.MaxUint32*time.Second) {
+		return cfg, fmt.Errorf("%w: %s", ErrInvalidUpdateLease, lease)
+	}
+
+	for _, server := range cfg.Notify {
+		ep, err := parseEndpoint(server, DefaultUpdatePort)
+		if err != nil {
+			return cfg, fmt.Errorf("notify: %w", err)
+		}
+		cfg.notifyServers = append(cfg.notifyServers, ep)
+	}
+
+	if len(cfg.Resolvers) > 0 && cfg.DoHURL != "" {
+		return cfg, ErrConflictingResolversConfigured
+	}
+
+	if len(cfg.Resolvers) == 0 && cfg.DoHURL == "" {
+		cfg.Resolvers = b.Resolvers
+		cfg.DoHURL = b.DoHURL
+	}
+
+	switch {
+	case cfg.DoHURL != "":
+		r, err := newDoHResolver(cfg.DoHURL)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.resolver = r
+	case len(cfg.Resolvers) > 0:
+		r, err := newResolver(cfg.Resolvers)
+		if err != nil {
+			return cfg, fmt.Errorf("resolvers: %w", err)
+		}
+		cfg.resolver = r
+	}
+
+	if cfg.SourceAddress == "" {
+		cfg.SourceAddress = b.SourceAddress
+	}
+
+	if cfg.SourceAddress != "" {
+		addr, err := parseSourceAddress(cfg.SourceAddress)
+		if err != nil {
+			return cfg, err
+		}
+
+		// A TCP connection leaves its port in TIME_WAIT, which
+		// blocks the next connection from the same port.
+		if addr.Port() != 0 && cfg.Transport != TransportUDP {
+			return cfg, fmt.Errorf("%w: %s: a source port requires the udp transport", ErrInvalidSourceAddress, cfg.SourceAddress)
+		}
+		cfg.sourceAddr = addr
+	}
+
+	switch {
+	case cfg.MaxConcurrentUpdatesPerZone == 0:
+		cfg.MaxConcurrentUpdatesPerZone = DefaultMaxConcurrentUpdatesPerZone
+	case cfg.MaxConcurrentUpdatesPerZone < 0:
+		return cfg, fmt.Errorf("%w: %d", ErrInvalidConcurrency, cfg.MaxConcurrentUpdatesPerZone)
+	}
+
 	if cfg.AllowedZones == nil {
 		return cfg, ErrNoAllowedZonesConfigured
 	}
 
-	if cfg.TSIGKeyRef.LocalObjectReference.Name == "" {
+	if cfg.Backend == "" {
+		cfg.Backend = b.Backend
+	}
+
+	if _, ok := b.backends[cfg.Backend]; !ok {
+		return cfg, fmt.Errorf("%w: %s", ErrUnknownBackend, cfg.Backend)
+	}
+
+	// Exactly one of TSIG and SIG(0) keys must be configured
+	hasTSIGKey := cfg.TSIGKeyRef.LocalObjectReference.Name != ""
+	hasSIG0Key := cfg.SIG0KeyRef != nil && cfg.SIG0KeyRef.LocalObjectReference.Name != ""
+	switch {
+	case !hasTSIGKey && !hasSIG0Key:
 		return cfg, ErrNoTSIGKeyConfigured
+	case hasTSIGKey && hasSIG0Key:
+		return cfg, ErrConflictingKeysConfigured
 	}
 
-	// Load the TSIG key
 	ctx := context.Background()
+	var err error
+	if hasTSIGKey {
+		// Load the TSIG key
+		if cfg.tsigKey, err = b.loadSecret(ctx, namespace, "TSIG key", cfg.TSIGKeyRef); err != nil {
+			return cfg, err
+		}
+
+		if cfg.tsig, err = cfg.parseTSIGKey(); err != nil {
+			return cfg, err
+		}
+	} else {
+		// Load the SIG(0) key pair
+		publicRef := cmmeta.SecretKeySelector{
+			LocalObjectReference: cfg.SIG0KeyRef.LocalObjectReference,
+			Key:                  cfg.SIG0KeyRef.PublicKey,
+		}
+		if publicRef.Key == "" {
+			publicRef.Key = DefaultSIG0PublicKey
+		}
+
+		privateRef := cmmeta.SecretKeySelector{
+			LocalObjectReference: cfg.SIG0KeyRef.LocalObjectReference,
+			Key:                  cfg.SIG0KeyRef.PrivateKey,
+		}
+		if privateRef.Key == "" {
+			privateRef.Key = DefaultSIG0PrivateKey
+		}
+
+		if cfg.sig0PublicKey, err = b.loadSecret(ctx, namespace, "SIG(0) public key", publicRef); err != nil {
+			return cfg, err
+		}
+
+		if cfg.sig0PrivateKey, err = b.loadSecret(ctx, namespace, "SIG(0) private key", privateRef); err != nil {
+			return cfg, err
+		}
+	}
+
+	// Load the TLS materials, if any
+	if cfg.TLSCARef != nil {
+		if cfg.tlsCA, err = b.loadSecret(ctx, namespace, "TLS CA bundle", *cfg.TLSCARef); err != nil {
+			return cfg, err
+		}
+	}
+
+	if cfg.TLSClientCertRef != nil {
+		if cfg.tlsClientCert, err = b.loadSecret(ctx, namespace, "TLS client certificate", *cfg.TLSClientCertRef); err != nil {
+			return cfg, err
+		}
+
+		if cfg.tlsClientKey, err = b.loadSecret(ctx, namespace, "TLS client key", *cfg.TLSClientKeyRef); err != nil {
+			return cfg, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// parseTSIGKey parses the raw TSIG key and validates its algorithm
+// against the configuration.
+func (bpc *BindProviderConfig) parseTSIGKey() (*tsigKey, error) {
+	key, err := parseTSIGKey(bpc.tsigKey)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, err := canonicalTSIGAlgorithm(key.Algorithm, bpc.AllowLegacyHMACMD5)
+	if err != nil {
+		return nil, fmt.Errorf("TSIG key %s: %w", key.Name, err)
+	}
+
+	if bpc.TSIGAlgorithm != "" && bpc.TSIGAlgorithm != algorithm {
+		return nil, fmt.Errorf("%w: %s != %s", ErrTSIGAlgorithmMismatch, bpc.TSIGAlgorithm, algorithm)
+	}
+
+	key.Algorithm = algorithm
+	key.Fudge = uint16(bpc.TSIGFudge.Duration / time.Second)
+
+	return key, nil
+}
+
+// loadSecret fetches the data referenced by the given secret key
+// selector.  The description is used in error messages.
+func (b *BindProviderSolver) loadSecret(ctx context.Context, namespace, description string, ref cmmeta.SecretKeySelector) ([]byte, error) {
 	getOpts := metav1.GetOptions{}
-	tsigSecret, err := b.client.CoreV1().Secrets(namespace).Get(ctx, cfg.TSIGKeyRef.LocalObjectReference.Name, getOpts)
+	secret, err := b.client.CoreV1().Secrets(namespace).Get(ctx, ref.LocalObjectReference.Name, getOpts)
 
 	if err != nil {
-		return cfg, fmt.Errorf("failed to load TSIG key from %s/%s: %v", namespace, cfg.TSIGKeyRef.LocalObjectReference.Name, err)
+		return nil, fmt.Errorf("failed to load %s from %s/%s: %v", description, namespace, ref.LocalObjectReference.Name, err)
 	}
 
-	secretData, ok := tsigSecret.Data[cfg.TSIGKeyRef.Key]
+	data, ok := secret.Data[ref.Key]
 	if !ok {
-		return cfg, fmt.Errorf("TSIG key %s not found in %s/%s", cfg.TSIGKeyRef.Key, cfg.TSIGKeyRef.LocalObjectReference.Name, namespace)
+		return nil, fmt.Errorf("%s %s not found in %s/%s", description, ref.Key, namespace, ref.LocalObjectReference.Name)
 	}
 
-	cfg.tsigKey = secretData
-
-	return cfg, nil
+	return data, nil
 }
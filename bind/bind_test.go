@@ -0,0 +1,223 @@
+package bind
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestNewUpdater(t *testing.T) {
+	b := NewSolver()
+
+	testCases := []struct {
+		backend string
+		wantErr error
+	}{
+		{backend: BackendNative},
+		{backend: BackendScript},
+		{backend: BackendMemory},
+		{backend: "unknown", wantErr: ErrUnknownBackend},
+	}
+
+	for _, tc := range testCases {
+		cfg := &BindProviderConfig{
+			Backend: tc.backend,
+			tsigKey: []byte(testKey),
+		}
+
+		_, err := b.newUpdater(cfg)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("backend %s: want error %v, got %v", tc.backend, tc.wantErr, err)
+		}
+	}
+}
+
+func TestParseConfigTSIGKey(t *testing.T) {
+	md5Key := `key "md5-key" { algorithm hmac-md5; secret "c2VjcmV0"; };`
+
+	testCases := []struct {
+		cfg     BindProviderConfig
+		wantErr error
+	}{
+		{cfg: BindProviderConfig{tsigKey: []byte(testKey)}},
+		{cfg: BindProviderConfig{tsigKey: []byte(testKey), TSIGAlgorithm: "hmac-sha256."}},
+		{cfg: BindProviderConfig{tsigKey: []byte(testKey), TSIGAlgorithm: "hmac-sha512."}, wantErr: ErrTSIGAlgorithmMismatch},
+		{cfg: BindProviderConfig{tsigKey: []byte(md5Key)}, wantErr: ErrLegacyTSIGAlgorithm},
+		{cfg: BindProviderConfig{tsigKey: []byte(md5Key), AllowLegacyHMACMD5: true}},
+	}
+
+	for _, tc := range testCases {
+		tc.cfg.TSIGFudge.Duration = 10 * time.Second
+		key, err := tc.cfg.parseTSIGKey()
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("want error %v, got %v", tc.wantErr, err)
+			continue
+		}
+
+		if err == nil && key.Fudge != 10 {
+			t.Errorf("want fudge 10, got %d", key.Fudge)
+		}
+	}
+}
+
+func TestRegisterBackend(t *testing.T) {
+	b := NewSolver()
+	custom := NewMemoryUpdater()
+	b.RegisterBackend("custom", func(cfg *BindProviderConfig) (Updater, error) {
+		return custom, nil
+	})
+
+	updater, err := b.newUpdater(&BindProviderConfig{Backend: "custom"})
+	if err != nil {
+		t.Fatalf("failed to create updater: %s", err)
+	}
+
+	if updater != custom {
+		t.Fatal("want the registered custom backend")
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	b := NewSolver()
+
+	testCases := []struct {
+		config  string
+		wantErr error
+	}{
+		{
+			config:  `{"tsigKeyRef": {"name": "tsig", "key": "tsig.key"}}`,
+			wantErr: ErrNoAllowedZonesConfigured,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "backend": "unknown"}`,
+			wantErr: ErrUnknownBackend,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "transport": "sctp"}`,
+			wantErr: ErrUnknownTransport,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "transport": "tls", "tlsClientCertRef": {"name": "tls", "key": "tls.crt"}}`,
+			wantErr: ErrIncompleteTLSClientCertificate,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "tsigAlgorithm": "hmac-sha3-256"}`,
+			wantErr: ErrUnsupportedTSIGAlgorithm,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "tsigAlgorithm": "hmac-md5"}`,
+			wantErr: ErrLegacyTSIGAlgorithm,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "tsigFudge": "500ms"}`,
+			wantErr: ErrInvalidTSIGFudge,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "tsigFudge": "24h"}`,
+			wantErr: ErrInvalidTSIGFudge,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "sourceAddress": "ns1.example.com"}`,
+			wantErr: ErrInvalidSourceAddress,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "sourceAddress": "[2001:db8::1]:65536"}`,
+			wantErr: ErrInvalidSourceAddress,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "transport": "tcp", "sourceAddress": "192.0.2.1:5300"}`,
+			wantErr: ErrInvalidSourceAddress,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "sourceAddress": "192.0.2.1:5300"}`,
+			wantErr: ErrInvalidSourceAddress,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "updateLease": "500ms"}`,
+			wantErr: ErrInvalidUpdateLease,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "updateLease": "-1h"}`,
+			wantErr: ErrInvalidUpdateLease,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "nameservers": ["ns1.example.com", "[2001:db8::1"]}`,
+			wantErr: ErrInvalidNameserver,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "notify": ["ns2.example.com:0"]}`,
+			wantErr: ErrInvalidNameserver,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "resolvers": ["192.0.2.53:domain"]}`,
+			wantErr: ErrInvalidNameserver,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "dohURL": "http://dns.example.com/dns-query"}`,
+			wantErr: ErrInvalidDoHURL,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "resolvers": ["192.0.2.53"], "dohURL": "https://dns.example.com/dns-query"}`,
+			wantErr: ErrConflictingResolversConfigured,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "hiddenPrimary": true}`,
+			wantErr: ErrNoHiddenPrimaryConfigured,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "failover": "parallel"}`,
+			wantErr: ErrNoParallelNameserversConfigured,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "failover": "random"}`,
+			wantErr: ErrUnknownFailover,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "discovery": "mdns"}`,
+			wantErr: ErrUnknownDiscovery,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "discovery": "srv", "transport": "tls"}`,
+			wantErr: ErrSRVDiscoveryTLS,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "maxConcurrentUpdatesPerZone": -1}`,
+			wantErr: ErrInvalidConcurrency,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."]}`,
+			wantErr: ErrNoTSIGKeyConfigured,
+		},
+		{
+			config:  `{"allowedZones": ["example.com."], "tsigKeyRef": {"name": "tsig", "key": "tsig.key"}, "sig0KeyRef": {"name": "sig0"}}`,
+			wantErr: ErrConflictingKeysConfigured,
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := b.loadConfig(&extapi.JSON{Raw: []byte(tc.config)}, "default")
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("config %s: want error %v, got %v", tc.config, tc.wantErr, err)
+		}
+	}
+}
+
+func TestLoadConfigResolvers(t *testing.T) {
+	b := NewSolver()
+	b.Resolvers = []string{"192.0.2.53", "[2001:db8::53]:5353"}
+
+	// Without a key, loading fails after the resolvers are parsed.
+	// The resolvers of the issuer take precedence.
+	cfg, _ := b.loadConfig(&extapi.JSON{Raw: []byte(`{"allowedZones": ["example.com."], "resolvers": ["198.51.100.53"]}`)}, "default")
+	if cfg.resolver == nil || !slices.Equal(cfg.resolver.Servers, []string{"198.51.100.53:53"}) {
+		t.Errorf("want the resolvers of the issuer, got %+v", cfg.resolver)
+	}
+
+	cfg, _ = b.loadConfig(&extapi.JSON{Raw: []byte(`{"allowedZones": ["example.com."]}`)}, "default")
+	if cfg.resolver == nil || !slices.Equal(cfg.resolver.Servers, []string{"192.0.2.53:53", "[2001:db8::53]:5353"}) {
+		t.Errorf("want the default resolvers, got %+v", cfg.resolver)
+	}
+}
@@ -0,0 +1,177 @@
+package bind
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when updates are not sent to a
+// nameserver, because it failed repeatedly.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// circuitBreakerThreshold is the number of consecutive failures,
+// after which the circuit breaker of a nameserver opens.
+const circuitBreakerThreshold = 5
+
+// circuitBreakerCooldown is the time an open circuit breaker waits,
+// before letting a single update through to probe the nameserver.
+const circuitBreakerCooldown = 30 * time.Second
+
+// CircuitState is the state of the circuit breaker of a nameserver.
+type CircuitState int
+
+const (
+	// CircuitClosed lets the updates through to the nameserver
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen fails the updates right away, since the
+	// nameserver failed repeatedly
+	CircuitOpen
+
+	// CircuitHalfOpen lets a single update through to probe,
+	// whether the nameserver has recovered
+	CircuitHalfOpen
+)
+
+// String returns the name of the state
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// circuitBreaker tracks the failures of a nameserver, and stops
+// sending updates to it, once it failed repeatedly.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	server   string
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// check returns ErrCircuitOpen, if the circuit breaker is open and
+// the nameserver may not be probed yet.
+func (b *circuitBreaker) check() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && time.Since(b.openedAt) < circuitBreakerCooldown {
+		return fmt.Errorf("%w for %s after %d failures", ErrCircuitOpen, b.server, b.failures)
+	}
+
+	return nil
+}
+
+// allow returns nil, if an update may be sent to the nameserver.
+// Once the cooldown has passed, an open circuit breaker turns
+// half-open, and lets a single update through.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < circuitBreakerCooldown {
+			return fmt.Errorf("%w for %s after %d failures", ErrCircuitOpen, b.server, b.failures)
+		}
+		b.setState(CircuitHalfOpen)
+		b.probing = true
+	case CircuitHalfOpen:
+		if b.probing {
+			return fmt.Errorf("%w for %s, waiting for probe", ErrCircuitOpen, b.server)
+		}
+		b.probing = true
+	}
+
+	return nil
+}
+
+// record records the outcome of an update.  Failures are errors
+// caused by the nameserver being unavailable, as opposed to the
+// nameserver rejecting the update.
+func (b *circuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		b.setState(CircuitClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= circuitBreakerThreshold {
+		b.openedAt = time.Now()
+		b.setState(CircuitOpen)
+	}
+}
+
+// abort records an update, which was cancelled before its outcome
+// was known.
+func (b *circuitBreaker) abort() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// setState sets the state of the circuit breaker.  It must be called
+// with the lock held.
+func (b *circuitBreaker) setState(state CircuitState) {
+	b.state = state
+	circuitBreakerState.WithLabelValues(b.server).Set(float64(state))
+}
+
+// breakers contains the circuit breakers of the nameservers, which
+// are shared by all clients.
+var breakers = struct {
+	sync.Mutex
+	m map[string]*circuitBreaker
+}{m: make(map[string]*circuitBreaker)}
+
+// breakerFor returns the circuit breaker of the nameserver.
+func breakerFor(server string) *circuitBreaker {
+	breakers.Lock()
+	defer breakers.Unlock()
+
+	b, ok := breakers.m[server]
+	if !ok {
+		b = &circuitBreaker{server: server}
+		breakers.m[server] = b
+	}
+
+	return b
+}
+
+// CircuitBreakerStates returns the state of the circuit breakers of
+// the nameservers, which updates were sent to, by the address of the
+// nameserver.
+func CircuitBreakerStates() map[string]CircuitState {
+	breakers.Lock()
+	defer breakers.Unlock()
+
+	states := make(map[string]CircuitState, len(breakers.m))
+	for server, b := range breakers.m {
+		b.mu.Lock()
+		state := b.state
+		if state == CircuitOpen && time.Since(b.openedAt) >= circuitBreakerCooldown {
+			state = CircuitHalfOpen
+		}
+		states[server] = state
+		b.mu.Unlock()
+	}
+
+	return states
+}
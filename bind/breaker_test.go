@@ -0,0 +1,97 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := &circuitBreaker{server: "192.0.2.1:53"}
+
+	for i := 0; i < circuitBreakerThreshold; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("attempt %d: want allowed, got %s", i, err)
+		}
+		b.record(true)
+	}
+
+	if b.state != CircuitOpen {
+		t.Fatalf("want %s, got %s", CircuitOpen, b.state)
+	}
+
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("want ErrCircuitOpen, got %v", err)
+	}
+
+	// Once the cooldown has passed, a single probe is let through
+	b.openedAt = time.Now().Add(-circuitBreakerCooldown)
+	if err := b.check(); err != nil {
+		t.Fatalf("want probe allowed, got %s", err)
+	}
+
+	if err := b.allow(); err != nil {
+		t.Fatalf("want probe allowed, got %s", err)
+	}
+
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("want a single probe, got %v", err)
+	}
+
+	if b.state != CircuitHalfOpen {
+		t.Fatalf("want %s, got %s", CircuitHalfOpen, b.state)
+	}
+
+	// A failed probe opens the circuit again
+	b.record(true)
+	if b.state != CircuitOpen {
+		t.Fatalf("want %s, got %s", CircuitOpen, b.state)
+	}
+
+	// A successful probe closes it
+	b.openedAt = time.Now().Add(-circuitBreakerCooldown)
+	if err := b.allow(); err != nil {
+		t.Fatalf("want probe allowed, got %s", err)
+	}
+	b.record(false)
+	if b.state != CircuitClosed || b.failures != 0 {
+		t.Fatalf("want %s, got %s after %d failures", CircuitClosed, b.state, b.failures)
+	}
+}
+
+func TestRFC2136ClientCircuitBreaker(t *testing.T) {
+	ts := newTestServer(t)
+	ts.FailUpdates(100, dns.RcodeServerFailure)
+
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	client := newRFC2136Client(key)
+	client.nameservers = []string{ts.Addr}
+	client.retry = retryPolicy{Attempts: circuitBreakerThreshold, Backoff: time.Millisecond}
+
+	rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}
+	if _, err := client.AddTXT(context.Background(), rec); !errors.Is(err, ErrServFail) {
+		t.Fatalf("want ErrServFail, got %v", err)
+	}
+
+	if state := CircuitBreakerStates()[ts.Addr]; state != CircuitOpen {
+		t.Fatalf("want %s, got %s", CircuitOpen, state)
+	}
+
+	// Further updates fail right away, without contacting the
+	// nameserver
+	requests := ts.Requests("udp")
+	if _, err := client.AddTXT(context.Background(), rec); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("want ErrCircuitOpen, got %v", err)
+	}
+
+	if got := ts.Requests("udp"); got != requests {
+		t.Errorf("want no requests sent, got %d", got-requests)
+	}
+}
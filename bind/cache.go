@@ -0,0 +1,156 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/sync/singleflight"
+	"k8s.io/klog/v2"
+)
+
+// DefaultNegativeDiscoveryTTL is the time for which a failed
+// discovery is cached, before the nameservers of the zone are looked
+// up again.
+const DefaultNegativeDiscoveryTTL = 30 * time.Second
+
+// maxDiscoveryTTL is the maximum time for which discovered
+// nameservers are cached, regardless of the TTL of their records.
+const maxDiscoveryTTL = time.Hour
+
+// discoveryEntry is a cached discovery of the nameservers of a zone.
+type discoveryEntry struct {
+	zone      string
+	discovery string
+
+	// endpoints are the nameservers discovered from the SOA and
+	// NS records
+	endpoints []endpoint
+
+	// records are the _dns-update SRV records, which are ordered
+	// anew on each use, so that the servers are picked by weight
+	records []*dns.SRV
+
+	// err is the error of a failed discovery
+	err error
+
+	expires time.Time
+}
+
+// nameservers returns the discovered nameservers, in the order they
+// are tried, or the error of the discovery.
+func (e *discoveryEntry) nameservers() ([]endpoint, error) {
+	switch {
+	case e.err != nil:
+		return nil, e.err
+	case e.records != nil:
+		return srvEndpoints(e.records), nil
+	default:
+		return e.endpoints, nil
+	}
+}
+
+// discoveryCache caches the discovered nameservers of the zones by
+// the TTL of their records, and failed discoveries for a short time.
+// Concurrent discoveries of the same zone share a single lookup.
+var discoveryCache = struct {
+	sync.Mutex
+	m     map[string]*discoveryEntry
+	group singleflight.Group
+}{m: make(map[string]*discoveryEntry)}
+
+// discoverCached discovers the nameservers of the zone, unless they
+// were discovered before using the same resolver and their records
+// did not expire yet.
+func discoverCached(ctx context.Context, r *resolver, zone, port, discovery, network string) ([]endpoint, error) {
+	key := fmt.Sprintf("%s %s %s %s %s %s", dns.CanonicalName(zone), discovery, port, network, r.URL, strings.Join(r.Servers, ","))
+
+	discoveryCache.Lock()
+	e, ok := discoveryCache.m[key]
+	discoveryCache.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		discoveryCacheLookups.WithLabelValues("hit").Inc()
+		return e.nameservers()
+	}
+
+	discoveryCacheLookups.WithLabelValues("miss").Inc()
+	v, _, _ := discoveryCache.group.Do(key, func() (any, error) {
+		e := discover(ctx, r, zone, port, discovery, network)
+
+		// Lookups, which were cancelled, say nothing about the
+		// zone and are not cached
+		cancelled := errors.Is(e.err, context.Canceled) || errors.Is(e.err, context.DeadlineExceeded)
+		if !cancelled && time.Now().Before(e.expires) {
+			storeDiscovery(key, e)
+		}
+
+		return e, nil
+	})
+
+	return v.(*discoveryEntry).nameservers()
+}
+
+// discover discovers the nameservers of the zone using the resolver,
+// and returns the cache entry of the result.
+func discover(ctx context.Context, r *resolver, zone, port, discovery, network string) *discoveryEntry {
+	e := &discoveryEntry{zone: dns.Fqdn(zone), discovery: discovery}
+
+	var ttl time.Duration
+	if discovery == DiscoverySRV {
+		e.records, ttl, e.err = lookupUpdateServers(ctx, r, zone, network)
+	} else {
+		e.endpoints, ttl, e.err = discoverNameservers(ctx, r, zone, port)
+	}
+
+	if e.err != nil {
+		ttl = DefaultNegativeDiscoveryTTL
+	}
+	ttl = min(ttl, maxDiscoveryTTL)
+	e.expires = time.Now().Add(ttl)
+
+	if e.err != nil {
+		klog.ErrorS(e.err, "failed to discover nameservers", "zone", zone, "discovery", discovery, "ttl", ttl)
+	} else {
+		nameservers, _ := e.nameservers()
+		klog.InfoS("discovered nameservers", "zone", zone, "discovery", discovery, "nameservers", nameservers, "ttl", ttl)
+	}
+
+	return e
+}
+
+// storeDiscovery caches the discovery under the key, and removes the
+// expired discoveries from the cache.
+func storeDiscovery(key string, e *discoveryEntry) {
+	discoveryCache.Lock()
+	defer discoveryCache.Unlock()
+
+	now := time.Now()
+	for k, cached := range discoveryCache.m {
+		if !now.Before(cached.expires) {
+			delete(discoveryCache.m, k)
+		}
+	}
+
+	discoveryCache.m[key] = e
+}
+
+// cachedDiscoveries returns the number of cached discoveries, which
+// did not expire yet.
+func cachedDiscoveries() int {
+	discoveryCache.Lock()
+	defer discoveryCache.Unlock()
+
+	now := time.Now()
+	n := 0
+	for _, e := range discoveryCache.m {
+		if now.Before(e.expires) {
+			n++
+		}
+	}
+
+	return n
+}
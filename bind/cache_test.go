@@ -0,0 +1,159 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/component-base/metrics/testutil"
+)
+
+// cacheEntry returns the discovery cache entry of the zone, which did
+// not expire yet, if any.
+func cacheEntry(zone string) (*discoveryEntry, bool) {
+	discoveryCache.Lock()
+	defer discoveryCache.Unlock()
+
+	for _, e := range discoveryCache.m {
+		if e.zone == zone && time.Now().Before(e.expires) {
+			return e, true
+		}
+	}
+
+	return nil, false
+}
+
+// flushDiscoveryCache removes all cached nameservers.
+func flushDiscoveryCache() {
+	discoveryCache.Lock()
+	defer discoveryCache.Unlock()
+
+	clear(discoveryCache.m)
+}
+
+func TestDiscoveryCache(t *testing.T) {
+	ts := newTestServer(t)
+	ts.AddRecords(t,
+		"cached.example.com. 60 IN SOA ns0.example.com. hostmaster.example.com. 1 7200 3600 1209600 300",
+		"cached.example.com. 120 IN NS ns1.example.com.",
+		"uncached.example.com. 0 IN NS ns1.example.com.",
+	)
+	r := &resolver{Servers: []string{ts.Addr}}
+
+	discover := func(zone string) ([]endpoint, error) {
+		return findNameservers(context.Background(), r, zone, DefaultUpdatePort, DiscoverySOA, TransportAuto)
+	}
+
+	want := []endpoint{{Host: "ns0.example.com", Port: "53"}, {Host: "ns1.example.com", Port: "53"}}
+	for i := 0; i < 3; i++ {
+		got, err := discover("cached.example.com.")
+		if err != nil || !slices.Equal(got, want) {
+			t.Fatalf("want %v, got %v (%v)", want, got, err)
+		}
+	}
+
+	// The SOA and NS records are only looked up once
+	if n := ts.Requests("udp"); n != 2 {
+		t.Errorf("want 2 lookups, got %d", n)
+	}
+
+	e, ok := cacheEntry("cached.example.com.")
+	if !ok {
+		t.Fatalf("want cache entry for cached.example.com.")
+	}
+
+	if got, err := e.nameservers(); !slices.Equal(got, want) || err != nil {
+		t.Errorf("want cached nameservers, got %v (%v)", got, err)
+	}
+
+	// The entry expires with the record of the lowest TTL
+	if ttl := time.Until(e.expires); ttl <= 50*time.Second || ttl > 60*time.Second {
+		t.Errorf("want entry to expire in 60s, got %s", ttl)
+	}
+
+	// Records with a TTL of zero are not cached
+	for i := 0; i < 2; i++ {
+		if _, err := discover("uncached.example.com."); err != nil {
+			t.Fatalf("failed to discover nameservers: %s", err)
+		}
+	}
+
+	if n := ts.Requests("udp"); n != 6 {
+		t.Errorf("want 6 lookups, got %d", n)
+	}
+
+	flushDiscoveryCache()
+	if _, ok := cacheEntry("cached.example.com."); ok {
+		t.Errorf("want no cache entry after flush")
+	}
+
+	if _, err := discover("cached.example.com."); err != nil {
+		t.Fatalf("failed to discover nameservers: %s", err)
+	}
+
+	if n := ts.Requests("udp"); n != 8 {
+		t.Errorf("want 8 lookups, got %d", n)
+	}
+}
+
+func TestDiscoveryCacheNegative(t *testing.T) {
+	ts := newTestServer(t)
+	r := &resolver{Servers: []string{ts.Addr}}
+
+	for i := 0; i < 3; i++ {
+		_, err := findNameservers(context.Background(), r, "missing.example.com.", DefaultUpdatePort, DiscoverySOA, TransportAuto)
+		if !errors.Is(err, ErrNoNameserverFound) {
+			t.Fatalf("want ErrNoNameserverFound, got %v", err)
+		}
+	}
+
+	if n := ts.Requests("udp"); n != 2 {
+		t.Errorf("want 2 lookups, got %d", n)
+	}
+
+	e, ok := cacheEntry("missing.example.com.")
+	if !ok || !errors.Is(e.err, ErrNoNameserverFound) {
+		t.Fatalf("want cached failure, got %+v", e)
+	}
+
+	if ttl := time.Until(e.expires); ttl > DefaultNegativeDiscoveryTTL {
+		t.Errorf("want failure cached for at most %s, got %s", DefaultNegativeDiscoveryTTL, ttl)
+	}
+}
+
+func TestDiscoveryCacheCollector(t *testing.T) {
+	flushDiscoveryCache()
+	defer flushDiscoveryCache()
+
+	fresh := &discoveryEntry{zone: "fresh.example.com.", expires: time.Now().Add(time.Hour)}
+	stale := &discoveryEntry{zone: "stale.example.com.", expires: time.Now().Add(-time.Second)}
+	discoveryCache.Lock()
+	discoveryCache.m["fresh"] = fresh
+	discoveryCache.m["stale"] = stale
+	discoveryCache.Unlock()
+
+	want := func(n int) string {
+		return fmt.Sprintf(`# HELP bind9_webhook_discovery_cache_entries [ALPHA] Number of zones, whose discovered nameservers or failed discovery are cached.
+# TYPE bind9_webhook_discovery_cache_entries gauge
+bind9_webhook_discovery_cache_entries %d
+`, n)
+	}
+
+	if err := testutil.CustomCollectAndCompare(&discoveryCacheCollector{}, strings.NewReader(want(1)), "bind9_webhook_discovery_cache_entries"); err != nil {
+		t.Errorf("want the fresh entry counted: %s", err)
+	}
+
+	// Entries are no longer counted once they expire, even though
+	// nothing was cached since
+	discoveryCache.Lock()
+	fresh.expires = time.Now().Add(-time.Second)
+	discoveryCache.Unlock()
+
+	if err := testutil.CustomCollectAndCompare(&discoveryCacheCollector{}, strings.NewReader(want(0)), "bind9_webhook_discovery_cache_entries"); err != nil {
+		t.Errorf("want no entries counted: %s", err)
+	}
+}
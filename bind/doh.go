@@ -0,0 +1,91 @@
+package bind
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+
+	"github.com/miekg/dns"
+)
+
+// ErrInvalidDoHURL is returned when the URL of the DNS-over-HTTPS
+// resolver is not an absolute https URL.
+var ErrInvalidDoHURL = errors.New("invalid DoH URL")
+
+// ErrConflictingResolversConfigured is returned when both the
+// resolvers and a DNS-over-HTTPS resolver were configured.
+var ErrConflictingResolversConfigured = errors.New("only one of resolvers and DoH URL can be configured")
+
+// dohMediaType is the media type of DNS messages sent over HTTPS, as
+// described in RFC 8484.
+const dohMediaType = "application/dns-message"
+
+// newDoHResolver creates a new resolver, which sends the queries to
+// the DNS-over-HTTPS (RFC 8484) endpoint at the given URL, e.g.
+// https://dns.example.com/dns-query.
+func newDoHResolver(rawURL string) (*resolver, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDoHURL, rawURL)
+	}
+
+	return &resolver{URL: u.String(), Timeout: DefaultLookupTimeout}, nil
+}
+
+// exchangeDoH sends the query to the DNS-over-HTTPS endpoint of the
+// resolver as a POST request, and returns the response.
+func (r *resolver) exchangeDoH(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	// The ID is always zero, so that the responses are cacheable
+	// by HTTP caches (RFC 8484, section 4.1)
+	req := msg.Copy()
+	req.Id = 0
+	data, err := req.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", dohMediaType)
+	httpReq.Header.Set("Accept", dohMediaType)
+
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s responded with HTTP status %s", r.URL, httpResp.Status)
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(httpResp.Header.Get("Content-Type")); mediaType != dohMediaType {
+		return nil, fmt.Errorf("%s responded with unexpected content type %q", r.URL, mediaType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(dns.Msg)
+	if err := resp.Unpack(body); err != nil {
+		return nil, fmt.Errorf("%s responded with an invalid message: %w", r.URL, err)
+	}
+
+	resp.Id = msg.Id
+
+	return resp, nil
+}
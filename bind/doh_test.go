@@ -0,0 +1,124 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// newTestDoHServer creates a DNS-over-HTTPS server, which answers
+// queries with the given records.
+func newTestDoHServer(t *testing.T, records ...string) *httptest.Server {
+	t.Helper()
+
+	var rrs []dns.RR
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			t.Fatalf("failed to parse record %s: %s", record, err)
+		}
+		rrs = append(rrs, rr)
+	}
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != dohMediaType {
+			http.Error(w, "unsupported request", http.StatusBadRequest)
+			return
+		}
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req := new(dns.Msg)
+		if err := req.Unpack(data); err != nil || req.Id != 0 {
+			http.Error(w, "invalid message", http.StatusBadRequest)
+			return
+		}
+
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		q := req.Question[0]
+		for _, rr := range rrs {
+			if rr.Header().Rrtype == q.Qtype && dns.CanonicalName(rr.Header().Name) == dns.CanonicalName(q.Name) {
+				resp.Answer = append(resp.Answer, rr)
+			}
+		}
+
+		out, err := resp.Pack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", dohMediaType)
+		w.Write(out)
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+func TestDoHResolver(t *testing.T) {
+	ts := newTestDoHServer(t,
+		"example.com. 3600 IN SOA ns0.example.com. hostmaster.example.com. 1 7200 3600 1209600 300",
+		"example.com. 3600 IN NS ns1.example.com.",
+		"ns1.example.com. 3600 IN A 192.0.2.1",
+	)
+
+	r, err := newDoHResolver(ts.URL + "/dns-query")
+	if err != nil {
+		t.Fatalf("failed to create resolver: %s", err)
+	}
+	r.HTTPClient = ts.Client()
+
+	got, _, err := discoverNameservers(context.Background(), r, "example.com.", DefaultUpdatePort)
+	if err != nil {
+		t.Fatalf("failed to discover nameservers: %s", err)
+	}
+
+	var hosts []string
+	for _, ep := range got {
+		hosts = append(hosts, ep.Host)
+	}
+
+	if want := []string{"ns0.example.com", "ns1.example.com"}; !slices.Equal(hosts, want) {
+		t.Errorf("want %v, got %v", want, hosts)
+	}
+
+	addrs, err := r.lookupNetIP(context.Background(), "ns1.example.com")
+	if err != nil || len(addrs) != 1 || addrs[0].String() != "192.0.2.1" {
+		t.Errorf("want [192.0.2.1], got %v (%v)", addrs, err)
+	}
+}
+
+func TestDoHResolverFailed(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	r, err := newDoHResolver(ts.URL)
+	if err != nil {
+		t.Fatalf("failed to create resolver: %s", err)
+	}
+	r.HTTPClient = ts.Client()
+
+	if _, err := r.lookup(context.Background(), "example.com.", dns.TypeNS); !errors.Is(err, ErrLookupFailed) {
+		t.Fatalf("want ErrLookupFailed, got %v", err)
+	}
+
+	for _, u := range []string{"http://dns.example.com/dns-query", "dns.example.com", "https:///dns-query"} {
+		if _, err := newDoHResolver(u); !errors.Is(err, ErrInvalidDoHURL) {
+			t.Errorf("%s: want ErrInvalidDoHURL, got %v", u, err)
+		}
+	}
+}
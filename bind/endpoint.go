@@ -0,0 +1,132 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidNameserver is returned when the nameserver endpoint is
+// not one of host, host:port or [v6]:port.
+var ErrInvalidNameserver = errors.New("invalid nameserver")
+
+// happyEyeballsDelay is the time to wait for a response from one
+// address of a nameserver, before trying the next one in parallel,
+// as recommended in RFC 8305, section 5.
+const happyEyeballsDelay = 250 * time.Millisecond
+
+// endpoint is the host and port of a nameserver.
+type endpoint struct {
+	// Host is either a hostname or an IP address
+	Host string
+
+	// Port is the port of the nameserver
+	Port string
+}
+
+// String returns the endpoint in host:port form
+func (e endpoint) String() string {
+	return net.JoinHostPort(e.Host, e.Port)
+}
+
+// parseEndpoint parses the nameserver endpoint, which is one of
+// host, host:port or [v6]:port.  Bare IPv6 addresses are supported as
+// well.  If no port is given, the default port is used.
+func parseEndpoint(s, defaultPort string) (endpoint, error) {
+	ep := endpoint{Host: s, Port: defaultPort}
+
+	// A bare IPv6 address, or a host without a port
+	if _, err := netip.ParseAddr(s); err == nil || !strings.Contains(s, ":") {
+		return ep, validateEndpoint(s, ep)
+	}
+
+	// An IPv6 address in brackets without a port
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		ep.Host = s[1 : len(s)-1]
+		if _, err := netip.ParseAddr(ep.Host); err != nil {
+			return ep, fmt.Errorf("%w: %s", ErrInvalidNameserver, s)
+		}
+		return ep, validateEndpoint(s, ep)
+	}
+
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return ep, fmt.Errorf("%w: %s", ErrInvalidNameserver, s)
+	}
+
+	ep.Host = host
+	ep.Port = port
+
+	return ep, validateEndpoint(s, ep)
+}
+
+// validateEndpoint validates the host and port of the endpoint parsed
+// from s.
+func validateEndpoint(s string, ep endpoint) error {
+	if ep.Host == "" || strings.ContainsAny(ep.Host, "[]/ ") {
+		return fmt.Errorf("%w: %s", ErrInvalidNameserver, s)
+	}
+
+	if port, err := strconv.ParseUint(ep.Port, 10, 16); err != nil || port == 0 {
+		return fmt.Errorf("%w: invalid port in %s", ErrInvalidNameserver, s)
+	}
+
+	return nil
+}
+
+// resolveEndpoint resolves the host of the endpoint to its IPv4 and
+// IPv6 addresses, interleaved by address family, starting with IPv6
+// as described in RFC 8305, section 4.  The host is looked up using
+// the given resolver, or the resolver of the system, if nil.
+func resolveEndpoint(ctx context.Context, r *resolver, ep endpoint) ([]netip.AddrPort, error) {
+	port, err := strconv.ParseUint(ep.Port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid port in %s", ErrInvalidNameserver, ep)
+	}
+
+	if addr, err := netip.ParseAddr(ep.Host); err == nil {
+		return []netip.AddrPort{netip.AddrPortFrom(addr, uint16(port))}, nil
+	}
+
+	var addrs []netip.Addr
+	if r != nil {
+		addrs, err = r.lookupNetIP(ctx, ep.Host)
+	} else {
+		addrs, err = net.DefaultResolver.LookupNetIP(ctx, "ip", ep.Host)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", ep.Host, err)
+	}
+
+	return interleaveAddrs(addrs, uint16(port)), nil
+}
+
+// interleaveAddrs interleaves the addresses by address family,
+// starting with IPv6, and returns them with the given port.
+func interleaveAddrs(addrs []netip.Addr, port uint16) []netip.AddrPort {
+	var v4, v6 []netip.Addr
+	for _, addr := range addrs {
+		if addr = addr.Unmap(); addr.Is4() {
+			v4 = append(v4, addr)
+		} else {
+			v6 = append(v6, addr)
+		}
+	}
+
+	result := make([]netip.AddrPort, 0, len(addrs))
+	for i := 0; i < len(v4) || i < len(v6); i++ {
+		if i < len(v6) {
+			result = append(result, netip.AddrPortFrom(v6[i], port))
+		}
+		if i < len(v4) {
+			result = append(result, netip.AddrPortFrom(v4[i], port))
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,137 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	testCases := []struct {
+		s       string
+		want    endpoint
+		wantErr error
+	}{
+		{s: "ns1.example.com", want: endpoint{Host: "ns1.example.com", Port: "53"}},
+		{s: "ns1.example.com:5353", want: endpoint{Host: "ns1.example.com", Port: "5353"}},
+		{s: "192.0.2.1", want: endpoint{Host: "192.0.2.1", Port: "53"}},
+		{s: "192.0.2.1:5353", want: endpoint{Host: "192.0.2.1", Port: "5353"}},
+		{s: "2001:db8::1", want: endpoint{Host: "2001:db8::1", Port: "53"}},
+		{s: "[2001:db8::1]", want: endpoint{Host: "2001:db8::1", Port: "53"}},
+		{s: "[2001:db8::1]:5353", want: endpoint{Host: "2001:db8::1", Port: "5353"}},
+		{s: "", wantErr: ErrInvalidNameserver},
+		{s: ":53", wantErr: ErrInvalidNameserver},
+		{s: "ns1.example.com:", wantErr: ErrInvalidNameserver},
+		{s: "ns1.example.com:domain", wantErr: ErrInvalidNameserver},
+		{s: "ns1.example.com:65536", wantErr: ErrInvalidNameserver},
+		{s: "ns1.example.com:0", wantErr: ErrInvalidNameserver},
+		{s: "[ns1.example.com]", wantErr: ErrInvalidNameserver},
+		{s: "[2001:db8::1", wantErr: ErrInvalidNameserver},
+		{s: "2001:db8::1]:53", wantErr: ErrInvalidNameserver},
+	}
+
+	for _, tc := range testCases {
+		got, err := parseEndpoint(tc.s, DefaultUpdatePort)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%q: want error %v, got %v", tc.s, tc.wantErr, err)
+			continue
+		}
+
+		if err == nil && got != tc.want {
+			t.Errorf("%q: want %+v, got %+v", tc.s, tc.want, got)
+		}
+	}
+}
+
+func TestEndpointString(t *testing.T) {
+	ep := endpoint{Host: "2001:db8::1", Port: "53"}
+	if got := ep.String(); got != "[2001:db8::1]:53" {
+		t.Fatalf("want [2001:db8::1]:53, got %s", got)
+	}
+}
+
+func TestInterleaveAddrs(t *testing.T) {
+	addrs := []netip.Addr{
+		netip.MustParseAddr("192.0.2.1"),
+		netip.MustParseAddr("192.0.2.2"),
+		netip.MustParseAddr("192.0.2.3"),
+		netip.MustParseAddr("2001:db8::1"),
+		netip.MustParseAddr("::ffff:192.0.2.4"),
+	}
+
+	want := []netip.AddrPort{
+		netip.MustParseAddrPort("[2001:db8::1]:53"),
+		netip.MustParseAddrPort("192.0.2.1:53"),
+		netip.MustParseAddrPort("192.0.2.2:53"),
+		netip.MustParseAddrPort("192.0.2.3:53"),
+		netip.MustParseAddrPort("192.0.2.4:53"),
+	}
+
+	if got := interleaveAddrs(addrs, 53); !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func TestTransportHappyEyeballs(t *testing.T) {
+	ts := newTestServer(t)
+
+	// A nameserver, which never responds
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	defer pc.Close()
+
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	client := newRFC2136Client(key)
+	client.transport.Net = TransportUDP
+
+	addrs := []netip.AddrPort{
+		netip.MustParseAddrPort(pc.LocalAddr().String()),
+		netip.MustParseAddrPort(ts.Addr),
+	}
+
+	msg := new(dns.Msg)
+	msg.SetUpdate("example.com.")
+	msg.Insert([]dns.RR{newTXT("_acme-challenge.example.com.", 300, "token")})
+	msg.SetTsig(key.Name, key.Algorithm, key.Fudge, time.Now().Unix())
+
+	start := time.Now()
+	resp, err := client.transport.exchangeAddrs(context.Background(), msg, addrs)
+	if err != nil {
+		t.Fatalf("failed to exchange message: %s", err)
+	}
+
+	if elapsed := time.Since(start); elapsed >= DefaultReadTimeout {
+		t.Errorf("want response before the read timeout, got it after %s", elapsed)
+	}
+
+	if resp.Rcode != dns.RcodeSuccess || ts.Updates() != 1 {
+		t.Errorf("want update applied, got rcode %d and %d updates", resp.Rcode, ts.Updates())
+	}
+}
+
+func TestResetTimer(t *testing.T) {
+	timer := time.NewTimer(time.Millisecond)
+	defer timer.Stop()
+
+	// The tick fired, but was not received
+	time.Sleep(20 * time.Millisecond)
+	resetTimer(timer, happyEyeballsDelay)
+
+	select {
+	case <-timer.C:
+		t.Errorf("want stale tick drained, got a tick right away")
+	case <-time.After(happyEyeballsDelay / 2):
+	}
+}
@@ -0,0 +1,236 @@
+package bind
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+	"k8s.io/klog/v2"
+)
+
+// ErrRefused is returned when the nameserver refused the update,
+// e.g. because the key is not allowed to update the zone.
+var ErrRefused = errors.New("update refused")
+
+// ErrNotAuth is returned when the nameserver is not authoritative
+// for the zone.
+var ErrNotAuth = errors.New("server not authoritative for zone")
+
+// ErrNotZone is returned when the record is not within the zone.
+var ErrNotZone = errors.New("record not within zone")
+
+// ErrServFail is returned when the nameserver failed to apply the
+// update.
+var ErrServFail = errors.New("server failure")
+
+// ErrYXRRSet is returned when an RRset, which should not exist,
+// exists.
+var ErrYXRRSet = errors.New("RRset exists when it should not")
+
+// ErrNXRRSet is returned when an RRset, which should exist, does not
+// exist.
+var ErrNXRRSet = errors.New("RRset does not exist when it should")
+
+// ErrUpdateRejected is returned when the nameserver rejected the
+// update with any other response code.
+var ErrUpdateRejected = errors.New("update rejected")
+
+// ErrBadSig is returned when the nameserver could not verify the
+// TSIG signature of the update.
+var ErrBadSig = errors.New("TSIG signature verification failed (BADSIG)")
+
+// ErrBadKey is returned when the nameserver does not know the TSIG
+// key or its algorithm.
+var ErrBadKey = errors.New("TSIG key not recognized (BADKEY)")
+
+// ErrBadTime is returned when the TSIG signature is outside of the
+// fudge window of the nameserver.
+var ErrBadTime = errors.New("TSIG signature time outside of fudge window (BADTIME)")
+
+// ErrResponseNotSigned is returned when the response to a
+// TSIG-signed update is not signed.
+var ErrResponseNotSigned = errors.New("response is not TSIG-signed")
+
+// ErrResponseVerification is returned when the TSIG signature of the
+// response could not be verified.
+var ErrResponseVerification = errors.New("response TSIG verification failed")
+
+// rcodeErrors maps the response codes to their errors.
+var rcodeErrors = map[int]error{
+	dns.RcodeRefused:       ErrRefused,
+	dns.RcodeNotAuth:       ErrNotAuth,
+	dns.RcodeNotZone:       ErrNotZone,
+	dns.RcodeServerFailure: ErrServFail,
+	dns.RcodeYXRrset:       ErrYXRRSet,
+	dns.RcodeNXRrset:       ErrNXRRSet,
+}
+
+// tsigErrors maps the TSIG error codes to their errors.
+var tsigErrors = map[uint16]error{
+	dns.RcodeBadSig:  ErrBadSig,
+	dns.RcodeBadKey:  ErrBadKey,
+	dns.RcodeBadTime: ErrBadTime,
+}
+
+// UpdateError is returned when the nameserver rejects a dynamic
+// update.  It wraps one of the errors above and provides a hint on
+// how to fix the problem.
+type UpdateError struct {
+	// Err is the underlying error, e.g. ErrRefused
+	Err error
+
+	// Server is the address of the nameserver
+	Server string
+
+	// Zone is the zone being updated
+	Zone string
+
+	// Key is the name of the key used to sign the update
+	Key string
+
+	// Rcode is the response or TSIG error code returned by the
+	// nameserver
+	Rcode int
+
+	// ClockSkew is the time by which the clock of the nameserver
+	// is ahead of the clock of the webhook.  It is only set for
+	// ErrBadTime.
+	ClockSkew time.Duration
+
+	// Fudge is the time window of the TSIG signature.  It is only
+	// set for ErrBadTime.
+	Fudge time.Duration
+}
+
+// Error implements the error interface
+func (e *UpdateError) Error() string {
+	msg := fmt.Sprintf("update of zone %s rejected by %s: %s", e.Zone, e.Server, e.Err)
+	if hint := e.Hint(); hint != "" {
+		msg = fmt.Sprintf("%s (hint: %s)", msg, hint)
+	}
+
+	return msg
+}
+
+// Unwrap returns the underlying error
+func (e *UpdateError) Unwrap() error {
+	return e.Err
+}
+
+// Hint returns a hint for operators on how to fix the problem.
+func (e *UpdateError) Hint() string {
+	switch e.Err {
+	case ErrRefused:
+		return fmt.Sprintf("check allow-update/update-policy for key %s in zone %s", e.Key, e.Zone)
+	case ErrNotAuth:
+		return fmt.Sprintf("make sure %s is the primary nameserver for zone %s", e.Server, e.Zone)
+	case ErrNotZone:
+		return fmt.Sprintf("make sure the record is within zone %s", e.Zone)
+	case ErrServFail:
+		return fmt.Sprintf("check the logs of %s", e.Server)
+	case ErrYXRRSet, ErrNXRRSet:
+		return "the record was modified concurrently, retry the update"
+	case ErrBadSig:
+		return fmt.Sprintf("check that the secret of key %s matches the one configured on %s", e.Key, e.Server)
+	case ErrBadKey:
+		return fmt.Sprintf("check that key %s and its algorithm are configured on %s", e.Key, e.Server)
+	case ErrBadTime:
+		if e.ClockSkew != 0 {
+			return fmt.Sprintf("the clock of %s is off by %s relative to the webhook, which exceeds the fudge of %s; check the clock synchronization of the webhook and %s", e.Server, e.ClockSkew, e.Fudge, e.Server)
+		}
+		return fmt.Sprintf("check the clock synchronization of the webhook and %s", e.Server)
+	default:
+		return ""
+	}
+}
+
+// checkResponse checks the response to an update and returns an
+// error, if the update failed.  The err is the error returned when
+// exchanging the update, which may be non-nil even though a response
+// was received, e.g. when its TSIG signature is invalid.
+func checkResponse(req, resp *dns.Msg, err error, server, zone, key string) error {
+	if resp == nil || (err != nil && !isTSIGError(err)) {
+		return fmt.Errorf("failed to send update to %s: %w", server, err)
+	}
+
+	updateErr := &UpdateError{
+		Server: server,
+		Zone:   zone,
+		Key:    key,
+	}
+
+	// TSIG errors are reported in the TSIG record of the
+	// response, along with a NOTAUTH response code.
+	if tsig := resp.IsTsig(); tsig != nil && tsig.Error != dns.RcodeSuccess {
+		updateErr.Rcode = int(tsig.Error)
+		updateErr.Err = tsigErrors[tsig.Error]
+		if updateErr.Err == nil {
+			updateErr.Err = fmt.Errorf("%w: %s", ErrUpdateRejected, dns.RcodeToString[int(tsig.Error)])
+		}
+		if tsig.Error == dns.RcodeBadTime {
+			reportClockSkew(updateErr, req, tsig)
+		}
+		return updateErr
+	}
+
+	if resp.Rcode != dns.RcodeSuccess {
+		updateErr.Rcode = resp.Rcode
+		updateErr.Err = rcodeErrors[resp.Rcode]
+		if updateErr.Err == nil {
+			updateErr.Err = fmt.Errorf("%w: %s", ErrUpdateRejected, dns.RcodeToString[resp.Rcode])
+		}
+		return updateErr
+	}
+
+	// The update succeeded, but make sure we can trust the
+	// response.
+	if err != nil {
+		return fmt.Errorf("%w from %s: %s", ErrResponseVerification, server, err)
+	}
+
+	if req.IsTsig() != nil && resp.IsTsig() == nil {
+		return fmt.Errorf("%w by %s", ErrResponseNotSigned, server)
+	}
+
+	// The verified signature shows that the clocks agree again
+	if req.IsTsig() != nil {
+		tsigClockSkew.Delete(map[string]string{"server": server})
+	}
+
+	return nil
+}
+
+// reportClockSkew measures the clock skew from the server time in
+// the TSIG record of a BADTIME response, and reports it in the error,
+// in the logs and as a metric.
+func reportClockSkew(updateErr *UpdateError, req *dns.Msg, tsig *dns.TSIG) {
+	reqTsig := req.IsTsig()
+	if reqTsig == nil {
+		return
+	}
+
+	serverTime, ok := tsigServerTime(tsig, reqTsig.TimeSigned)
+	if !ok {
+		return
+	}
+
+	skew := time.Until(serverTime).Round(time.Second)
+	updateErr.ClockSkew = skew
+	updateErr.Fudge = time.Duration(reqTsig.Fudge) * time.Second
+
+	tsigClockSkew.WithLabelValues(updateErr.Server).Set(skew.Seconds())
+	klog.InfoS("TSIG clock skew detected", "server", updateErr.Server, "zone", updateErr.Zone, "skew", skew, "fudge", updateErr.Fudge)
+}
+
+// isTSIGError returns true, if the error is returned by the DNS
+// client when verifying the TSIG signature of a response.
+func isTSIGError(err error) bool {
+	for _, tsigErr := range []error{dns.ErrSig, dns.ErrTime, dns.ErrAuth, dns.ErrNoSig, dns.ErrKeyAlg, dns.ErrSecret} {
+		if errors.Is(err, tsigErr) {
+			return true
+		}
+	}
+
+	return false
+}
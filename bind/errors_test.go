@@ -0,0 +1,220 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestRFC2136ClientRcodeErrors(t *testing.T) {
+	testCases := []struct {
+		rcode   int
+		wantErr error
+		hint    string
+	}{
+		{rcode: dns.RcodeRefused, wantErr: ErrRefused, hint: "check allow-update/update-policy for key acme-key."},
+		{rcode: dns.RcodeNotAuth, wantErr: ErrNotAuth, hint: "primary nameserver for zone example.com."},
+		{rcode: dns.RcodeNotZone, wantErr: ErrNotZone, hint: "within zone example.com."},
+		{rcode: dns.RcodeServerFailure, wantErr: ErrServFail, hint: "check the logs"},
+		{rcode: dns.RcodeYXRrset, wantErr: ErrYXRRSet, hint: "retry the update"},
+		{rcode: dns.RcodeNXRrset, wantErr: ErrNXRRSet, hint: "retry the update"},
+		{rcode: dns.RcodeFormatError, wantErr: ErrUpdateRejected},
+	}
+
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	for _, tc := range testCases {
+		ts := newTestServer(t)
+		ts.Respond(tc.rcode)
+
+		client := newRFC2136Client(key)
+		client.nameservers = []string{ts.Addr}
+		client.retry.Backoff = time.Millisecond
+
+		rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}
+		_, err := client.AddTXT(context.Background(), rec)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: want %v, got %v", dns.RcodeToString[tc.rcode], tc.wantErr, err)
+			continue
+		}
+
+		var updateErr *UpdateError
+		if !errors.As(err, &updateErr) {
+			t.Errorf("%s: want *UpdateError, got %T", dns.RcodeToString[tc.rcode], err)
+			continue
+		}
+
+		if updateErr.Rcode != tc.rcode || updateErr.Server != ts.Addr {
+			t.Errorf("%s: unexpected error %+v", dns.RcodeToString[tc.rcode], updateErr)
+		}
+
+		if !strings.Contains(err.Error(), tc.hint) {
+			t.Errorf("%s: want hint %q in %q", dns.RcodeToString[tc.rcode], tc.hint, err)
+		}
+	}
+}
+
+func TestRFC2136ClientTSIGErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		key     *tsigKey
+		wantErr error
+	}{
+		{
+			name:    "wrong secret",
+			key:     &tsigKey{Name: "acme-key.", Algorithm: dns.HmacSHA256, Secret: "d3Jvbmctc2VjcmV0", Fudge: DefaultTSIGFudge},
+			wantErr: ErrBadSig,
+		},
+		{
+			name:    "unknown key",
+			key:     &tsigKey{Name: "other-key.", Algorithm: dns.HmacSHA256, Secret: "d3Jvbmctc2VjcmV0", Fudge: DefaultTSIGFudge},
+			wantErr: ErrBadKey,
+		},
+	}
+
+	for _, tc := range testCases {
+		ts := newTestServer(t)
+		client := newRFC2136Client(tc.key)
+		client.nameservers = []string{ts.Addr}
+
+		rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}
+		_, err := client.AddTXT(context.Background(), rec)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: want %v, got %v", tc.name, tc.wantErr, err)
+		}
+
+		if !strings.Contains(err.Error(), tc.key.Name) {
+			t.Errorf("%s: want key name in %q", tc.name, err)
+		}
+	}
+}
+
+func TestRFC2136ClientResponseVerification(t *testing.T) {
+	testCases := []struct {
+		signing string
+		wantErr error
+	}{
+		{signing: "unsigned", wantErr: ErrResponseNotSigned},
+		{signing: "invalid", wantErr: ErrResponseVerification},
+	}
+
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	for _, tc := range testCases {
+		ts := newTestServer(t)
+		ts.SignResponses(tc.signing)
+
+		client := newRFC2136Client(key)
+		client.nameservers = []string{ts.Addr}
+
+		rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}
+		if _, err := client.AddTXT(context.Background(), rec); !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: want %v, got %v", tc.signing, tc.wantErr, err)
+		}
+	}
+}
+
+func TestCheckResponseBadTime(t *testing.T) {
+	req := new(dns.Msg)
+	req.SetUpdate("example.com.")
+	req.SetTsig("acme-key.", dns.HmacSHA256, DefaultTSIGFudge, time.Now().Unix())
+
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	resp.Rcode = dns.RcodeNotAuth
+	resp.Extra = []dns.RR{&dns.TSIG{
+		Hdr:        dns.RR_Header{Name: "acme-key.", Rrtype: dns.TypeTSIG, Class: dns.ClassANY},
+		Algorithm:  dns.HmacSHA256,
+		TimeSigned: uint64(time.Now().Add(time.Hour).Unix()),
+		Fudge:      DefaultTSIGFudge,
+		Error:      dns.RcodeBadTime,
+	}}
+
+	err := checkResponse(req, resp, dns.ErrAuth, "127.0.0.1:53", "example.com.", "acme-key.")
+	if !errors.Is(err, ErrBadTime) {
+		t.Fatalf("want ErrBadTime, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "clock synchronization") {
+		t.Fatalf("want hint in %q", err)
+	}
+
+	var updateErr *UpdateError
+	if !errors.As(err, &updateErr) {
+		t.Fatalf("want *UpdateError, got %T", err)
+	}
+
+	if skew := updateErr.ClockSkew; skew < 59*time.Minute || skew > 61*time.Minute {
+		t.Fatalf("want clock skew of 1h, got %s", skew)
+	}
+}
+
+func TestRFC2136ClientClockSkew(t *testing.T) {
+	ts := newTestServer(t)
+	ts.SkewClock(-10 * time.Minute)
+
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	client := newRFC2136Client(key)
+	client.nameservers = []string{ts.Addr}
+
+	rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}
+	_, err = client.AddTXT(context.Background(), rec)
+	if !errors.Is(err, ErrBadTime) {
+		t.Fatalf("want ErrBadTime, got %v", err)
+	}
+
+	var updateErr *UpdateError
+	if !errors.As(err, &updateErr) {
+		t.Fatalf("want *UpdateError, got %T", err)
+	}
+
+	if skew := updateErr.ClockSkew; skew < -601*time.Second || skew > -599*time.Second {
+		t.Errorf("want clock skew of -10m, got %s", skew)
+	}
+
+	if updateErr.Fudge != DefaultTSIGFudge*time.Second {
+		t.Errorf("want fudge of %ds, got %s", DefaultTSIGFudge, updateErr.Fudge)
+	}
+
+	if !strings.Contains(err.Error(), "off by -10m") {
+		t.Errorf("want clock skew in %q", err)
+	}
+
+	skew, err := testutil.GetGaugeMetricValue(tsigClockSkew.WithLabelValues(ts.Addr))
+	if err != nil {
+		t.Fatalf("failed to get metric: %s", err)
+	}
+
+	if skew < -601 || skew > -599 {
+		t.Errorf("want clock skew metric of -600, got %f", skew)
+	}
+
+	if ts.Updates() != 0 {
+		t.Errorf("want no updates applied, got %d", ts.Updates())
+	}
+
+	// The skew is no longer reported, once the clock was fixed
+	ts.SkewClock(0)
+	if _, err := client.AddTXT(context.Background(), rec); err != nil {
+		t.Fatalf("failed to add record: %s", err)
+	}
+
+	if tsigClockSkew.Delete(map[string]string{"server": ts.Addr}) {
+		t.Errorf("want clock skew metric of %s removed", ts.Addr)
+	}
+}
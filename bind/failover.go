@@ -0,0 +1,196 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// ErrUnknownFailover is returned when the solver was configured with
+// an unknown failover mode.
+var ErrUnknownFailover = errors.New("unknown failover")
+
+// ErrNoParallelNameserversConfigured is returned when the parallel
+// failover mode was enabled without configuring the nameservers.  The
+// discovered nameservers include the secondaries of the zone, which
+// are only tried after the primary refused the update.
+var ErrNoParallelNameserversConfigured = errors.New("parallel failover requires nameservers")
+
+const (
+	// FailoverSequential tries the nameservers in order, until
+	// one of them applies the update
+	FailoverSequential = "sequential"
+
+	// FailoverParallel sends the update to all nameservers at
+	// once, and uses the first one, which applies it.  Removals
+	// are applied by all nameservers.
+	FailoverParallel = "parallel"
+)
+
+// DefaultFailover is the failover mode used, unless specified in the
+// configuration.
+const DefaultFailover = FailoverSequential
+
+// DefaultNameserverTimeout is the time within which a nameserver
+// must apply an update, including its retries, before the next
+// nameserver is tried.
+const DefaultNameserverTimeout = 30 * time.Second
+
+// applyAny applies the changes to the zone on the first of the
+// nameservers, which succeeds.  Depending on the failover mode of
+// the client, the nameservers are tried in order, or all at once.
+// Returns the errors of all nameservers, if none succeeded.
+func (c *rfc2136Client) applyAny(ctx context.Context, nameservers []endpoint, zone string, changes []change) ([]Result, error) {
+	if c.failover == FailoverParallel && len(nameservers) > 1 {
+		return c.applyParallel(ctx, nameservers, zone, changes)
+	}
+
+	var errs []error
+	for _, nameserver := range nameservers {
+		results, err := c.applyWithTimeout(ctx, nameserver, zone, changes)
+		if err == nil {
+			return results, nil
+		}
+
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+
+		klog.InfoS("nameserver failed to apply update", "zone", zone, "server", nameserver, "err", err)
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// applyParallel applies the changes to the zone on all nameservers
+// at once.  The additions are applied by the first nameserver, which
+// succeeds, while the removals are applied by all nameservers, so
+// that a record added by more than one of them is removed from all.
+func (c *rfc2136Client) applyParallel(ctx context.Context, nameservers []endpoint, zone string, changes []change) ([]Result, error) {
+	var adds, removals []change
+	for _, ch := range changes {
+		if ch.Add {
+			adds = append(adds, ch)
+		} else {
+			removals = append(removals, ch)
+		}
+	}
+
+	var addResults, removalResults []Result
+	var addErr, removalErr error
+	if len(adds) > 0 {
+		addResults, addErr = c.applyFirst(ctx, nameservers, zone, adds)
+	}
+	if len(removals) > 0 {
+		removalResults, removalErr = c.applyAll(ctx, nameservers, zone, removals)
+	}
+
+	if err := errors.Join(addErr, removalErr); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(changes))
+	for _, ch := range changes {
+		if ch.Add {
+			results, addResults = append(results, addResults[0]), addResults[1:]
+		} else {
+			results, removalResults = append(results, removalResults[0]), removalResults[1:]
+		}
+	}
+
+	return results, nil
+}
+
+// reply is the outcome of applying changes on a nameserver.
+type reply struct {
+	results    []Result
+	err        error
+	nameserver endpoint
+}
+
+// sendAll applies the changes to the zone on all nameservers at once,
+// and returns the channel receiving their replies.
+func (c *rfc2136Client) sendAll(ctx context.Context, nameservers []endpoint, zone string, changes []change) <-chan reply {
+	replies := make(chan reply, len(nameservers))
+	for _, nameserver := range nameservers {
+		go func(nameserver endpoint) {
+			results, err := c.applyWithTimeout(ctx, nameserver, zone, changes)
+			replies <- reply{results: results, err: err, nameserver: nameserver}
+		}(nameserver)
+	}
+
+	return replies
+}
+
+// applyFirst applies the changes to the zone on all nameservers at
+// once, and returns the results of the first one, which succeeds.
+// The updates sent to the other nameservers are cancelled.
+func (c *rfc2136Client) applyFirst(ctx context.Context, nameservers []endpoint, zone string, changes []change) ([]Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	replies := c.sendAll(ctx, nameservers, zone, changes)
+	errs := make([]error, 0, len(nameservers))
+	for range nameservers {
+		r := <-replies
+		if r.err == nil {
+			return r.results, nil
+		}
+
+		klog.InfoS("nameserver failed to apply update", "zone", zone, "server", r.nameserver, "err", r.err)
+		errs = append(errs, r.err)
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// applyAll applies the changes to the zone on all nameservers at
+// once, and waits for all of them.  It succeeds, if any of them
+// succeeded, and a change is reported as changed by the nameservers,
+// which changed it.
+func (c *rfc2136Client) applyAll(ctx context.Context, nameservers []endpoint, zone string, changes []change) ([]Result, error) {
+	replies := c.sendAll(ctx, nameservers, zone, changes)
+
+	var results []Result
+	errs := make([]error, 0, len(nameservers))
+	for range nameservers {
+		r := <-replies
+		if r.err != nil {
+			klog.InfoS("nameserver failed to apply update", "zone", zone, "server", r.nameserver, "err", r.err)
+			errs = append(errs, r.err)
+			continue
+		}
+
+		if results == nil {
+			results = r.results
+			continue
+		}
+
+		for i, result := range r.results {
+			if result.Changed && !results[i].Changed {
+				results[i] = result
+			}
+		}
+	}
+
+	if results == nil {
+		return nil, errors.Join(errs...)
+	}
+
+	return results, nil
+}
+
+// applyWithTimeout applies the changes to the zone on the nameserver
+// within the nameserver timeout of the client, if any.
+func (c *rfc2136Client) applyWithTimeout(ctx context.Context, nameserver endpoint, zone string, changes []change) ([]Result, error) {
+	if c.serverTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.serverTimeout)
+		defer cancel()
+	}
+
+	return c.applyTo(ctx, nameserver, zone, changes)
+}
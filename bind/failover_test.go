@@ -0,0 +1,152 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestRFC2136ClientFailover(t *testing.T) {
+	testCases := []struct {
+		name        string
+		failover    string
+		setup       func(first, second *testServer)
+		wantServer  int
+		wantErr     error
+		wantUpdates []int
+	}{
+		{
+			name:        "sequential, first accepts",
+			failover:    FailoverSequential,
+			setup:       func(first, second *testServer) {},
+			wantServer:  0,
+			wantUpdates: []int{1, 0},
+		},
+		{
+			name:        "sequential, first down",
+			failover:    FailoverSequential,
+			setup:       func(first, second *testServer) { first.DropUpdates(100) },
+			wantServer:  1,
+			wantUpdates: []int{0, 1},
+		},
+		{
+			name:        "sequential, first refuses",
+			failover:    FailoverSequential,
+			setup:       func(first, second *testServer) { first.FailUpdates(100, dns.RcodeRefused) },
+			wantServer:  1,
+			wantUpdates: []int{0, 1},
+		},
+		{
+			name:        "parallel, first down",
+			failover:    FailoverParallel,
+			setup:       func(first, second *testServer) { first.DropUpdates(100) },
+			wantServer:  1,
+			wantUpdates: []int{0, 1},
+		},
+		{
+			name:     "all fail",
+			failover: FailoverParallel,
+			setup: func(first, second *testServer) {
+				first.FailUpdates(100, dns.RcodeRefused)
+				second.FailUpdates(100, dns.RcodeRefused)
+			},
+			wantErr:     ErrRefused,
+			wantUpdates: []int{0, 0},
+		},
+	}
+
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	for _, tc := range testCases {
+		first := newTestServer(t)
+		second := newTestServer(t)
+		servers := []*testServer{first, second}
+		tc.setup(first, second)
+
+		client := newRFC2136Client(key)
+		client.nameservers = []string{first.Addr, second.Addr}
+		client.transport.Net = TransportUDP
+		client.failover = tc.failover
+		client.serverTimeout = 200 * time.Millisecond
+		client.retry.Backoff = time.Millisecond
+
+		start := time.Now()
+		rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}
+		result, err := client.AddTXT(context.Background(), rec)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: want error %v, got %v", tc.name, tc.wantErr, err)
+			continue
+		}
+
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("%s: want the nameserver timeout enforced, took %s", tc.name, elapsed)
+		}
+
+		if err == nil && result.Server != servers[tc.wantServer].Addr {
+			t.Errorf("%s: want update applied by %s, got %s", tc.name, servers[tc.wantServer].Addr, result.Server)
+		}
+
+		for i, ts := range servers {
+			if ts.Updates() != tc.wantUpdates[i] {
+				t.Errorf("%s: want %d updates applied by %s, got %d", tc.name, tc.wantUpdates[i], ts.Addr, ts.Updates())
+			}
+		}
+	}
+}
+
+func TestRFC2136ClientParallelRemoval(t *testing.T) {
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	first := newTestServer(t)
+	second := newTestServer(t)
+	third := newTestServer(t)
+	rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}
+
+	// The record was added by all nameservers, e.g. when they
+	// applied the update before it was cancelled
+	for _, ts := range []*testServer{first, second, third} {
+		client := newRFC2136Client(key)
+		client.nameservers = []string{ts.Addr}
+		if _, err := client.AddTXT(context.Background(), rec); err != nil {
+			t.Fatalf("failed to add record to %s: %s", ts.Addr, err)
+		}
+	}
+	third.Respond(dns.RcodeRefused)
+
+	client := newRFC2136Client(key)
+	client.nameservers = []string{first.Addr, second.Addr, third.Addr}
+	client.transport.Net = TransportUDP
+	client.failover = FailoverParallel
+	client.serverTimeout = 200 * time.Millisecond
+	client.retry.Backoff = time.Millisecond
+
+	// The removal succeeds, although the third nameserver refuses
+	// it
+	result, err := client.RemoveTXT(context.Background(), rec)
+	if err != nil {
+		t.Fatalf("failed to remove record: %s", err)
+	}
+
+	if !result.Changed {
+		t.Errorf("want record removed, got %+v", result)
+	}
+
+	for _, ts := range []*testServer{first, second} {
+		if got := ts.TXT(rec.FQDN); len(got) != 0 {
+			t.Errorf("want record removed from %s, got %v", ts.Addr, got)
+		}
+	}
+
+	if got := third.TXT(rec.FQDN); len(got) != 1 {
+		t.Errorf("want record kept by %s, got %v", third.Addr, got)
+	}
+}
@@ -0,0 +1,164 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"k8s.io/klog/v2"
+)
+
+// ErrNotConverged is returned by Present in hidden primary mode,
+// while some of the public nameservers of the zone do not serve the
+// TXT record yet.  It is transient, and cert-manager calls Present
+// again later.
+var ErrNotConverged = errors.New("public nameservers have not converged")
+
+// ErrConvergenceTimeout is returned by Present in hidden primary
+// mode, once the public nameservers of the zone did not serve the TXT
+// record within the convergence timeout.  Unlike ErrNotConverged, it
+// is not transient, and it is returned until the record is cleaned up
+// or all nameservers serve it.
+var ErrConvergenceTimeout = errors.New("public nameservers did not converge in time")
+
+// ErrNoHiddenPrimaryConfigured is returned when the hidden primary
+// mode was enabled without configuring the nameservers.
+var ErrNoHiddenPrimaryConfigured = errors.New("hidden primary mode requires nameservers")
+
+// DefaultConvergenceTimeout is the time within which the public
+// nameservers must serve the TXT record in hidden primary mode,
+// before the webhook gives up on them.
+const DefaultConvergenceTimeout = 5 * time.Minute
+
+// convergenceTracker tracks since when the webhook is waiting for the
+// public nameservers to serve each TXT record.
+type convergenceTracker struct {
+	mu      sync.Mutex
+	started map[string]time.Time
+}
+
+// newConvergenceTracker creates a new tracker.
+func newConvergenceTracker() *convergenceTracker {
+	return &convergenceTracker{started: make(map[string]time.Time)}
+}
+
+// start returns the time the webhook started waiting for the record,
+// i.e. the first call for the record.
+func (t *convergenceTracker) start(rec ChallengeRecord) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := convergenceKey(rec)
+	started, ok := t.started[key]
+	if !ok {
+		started = time.Now()
+		t.started[key] = started
+	}
+
+	return started
+}
+
+// done stops waiting for the record.
+func (t *convergenceTracker) done(rec ChallengeRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.started, convergenceKey(rec))
+}
+
+// convergenceKey returns the key of the record in the tracker.
+func convergenceKey(rec ChallengeRecord) string {
+	return dns.CanonicalName(rec.FQDN) + " " + rec.Value
+}
+
+// waitConverged checks whether all public nameservers of the zone
+// serve the TXT record, which was added to the hidden primary.
+func (b *BindProviderSolver) waitConverged(ctx context.Context, cfg *BindProviderConfig, rec ChallengeRecord) error {
+	return b.convergence.wait(rec, cfg.ConvergenceTimeout.Duration, func() error {
+		return checkConverged(ctx, cfg, rec, DefaultUpdatePort)
+	})
+}
+
+// wait runs the check of the record, and returns its error until the
+// timeout has passed since the first check of the record, after which
+// it returns an error wrapping ErrConvergenceTimeout, which names the
+// nameservers lagging behind.
+func (t *convergenceTracker) wait(rec ChallengeRecord, timeout time.Duration, check func() error) error {
+	started := t.start(rec)
+
+	err := check()
+	if err == nil {
+		t.done(rec)
+		klog.InfoS("public nameservers converged", "fqdn", rec.FQDN, "uid", rec.UID, "elapsed", time.Since(started).Round(time.Millisecond))
+		return nil
+	}
+
+	// The error of the check is only included in the message, so
+	// that the error is not mistaken for a transient one
+	if time.Since(started) >= timeout {
+		return fmt.Errorf("%w for %s within %s: %v", ErrConvergenceTimeout, rec.FQDN, timeout, err)
+	}
+
+	return err
+}
+
+// checkConverged queries the nameservers from the published NS
+// records of the zone on the given port for the TXT record, and
+// returns an error wrapping ErrNotConverged, if any of them does not
+// serve it yet.
+func checkConverged(ctx context.Context, cfg *BindProviderConfig, rec ChallengeRecord, port string) error {
+	r := cfg.resolver
+	if r == nil {
+		var err error
+		if r, err = systemResolver(); err != nil {
+			return fmt.Errorf("%w for %s: %w", ErrNotConverged, rec.FQDN, err)
+		}
+	}
+
+	rrs, err := r.lookup(ctx, rec.Zone, dns.TypeNS)
+	if err != nil {
+		return fmt.Errorf("%w for %s: failed to look up the public nameservers: %w", ErrNotConverged, rec.FQDN, err)
+	}
+
+	if len(rrs) == 0 {
+		return fmt.Errorf("%w for %s: %w for %s", ErrNotConverged, rec.FQDN, ErrNoNameserverFound, rec.Zone)
+	}
+
+	// The queries are not signed, since the public nameservers
+	// may not know the key used for the updates.
+	tr := newTransport()
+	tr.SourceAddr = withoutPort(cfg.sourceAddr)
+	tr.DialTimeout = cfg.DialTimeout.Duration
+	tr.ReadTimeout = cfg.ReadTimeout.Duration
+	tr.WriteTimeout = cfg.WriteTimeout.Duration
+	tr.Resolver = cfg.resolver
+	client := &rfc2136Client{transport: tr}
+
+	errs := make([]error, len(rrs))
+	var wg sync.WaitGroup
+	for i, rr := range rrs {
+		wg.Add(1)
+		go func(i int, ns endpoint) {
+			defer wg.Done()
+
+			exists, err := client.hasTXT(ctx, ns, rec)
+			switch {
+			case err != nil:
+				errs[i] = fmt.Errorf("%s: %w", ns, err)
+			case !exists:
+				errs[i] = fmt.Errorf("%s does not serve the record yet", ns)
+			}
+		}(i, endpoint{Host: strings.TrimSuffix(rr.(*dns.NS).Ns, "."), Port: port})
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w for %s: %w", ErrNotConverged, rec.FQDN, err)
+	}
+
+	return nil
+}
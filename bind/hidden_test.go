@@ -0,0 +1,96 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckConverged(t *testing.T) {
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	// The public nameservers listen on the same port of different
+	// loopback addresses, and the first one is the resolver.
+	ns1 := newTestServer(t)
+	_, port, _ := net.SplitHostPort(ns1.Addr)
+	ns2 := newTestServerAddr(t, net.JoinHostPort("127.0.0.2", port))
+	ns1.AddRecords(t,
+		"example.com. 3600 IN NS ns1.example.com.",
+		"example.com. 3600 IN NS ns2.example.com.",
+		"ns1.example.com. 3600 IN A 127.0.0.1",
+		"ns2.example.com. 3600 IN A 127.0.0.2",
+	)
+
+	cfg := &BindProviderConfig{resolver: &resolver{Servers: []string{ns1.Addr}}}
+	rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}
+
+	// The record only reached the first nameserver
+	client := newRFC2136Client(key)
+	client.nameservers = []string{ns1.Addr}
+	if _, err := client.AddTXT(context.Background(), rec); err != nil {
+		t.Fatalf("failed to add record: %s", err)
+	}
+
+	err = checkConverged(context.Background(), cfg, rec, port)
+	if !errors.Is(err, ErrNotConverged) {
+		t.Fatalf("want ErrNotConverged, got %v", err)
+	}
+
+	client.nameservers = []string{ns2.Addr}
+	if _, err := client.AddTXT(context.Background(), rec); err != nil {
+		t.Fatalf("failed to add record: %s", err)
+	}
+
+	if err := checkConverged(context.Background(), cfg, rec, port); err != nil {
+		t.Fatalf("want converged, got %s", err)
+	}
+}
+
+func TestConvergenceTrackerWait(t *testing.T) {
+	tracker := newConvergenceTracker()
+	rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", Value: "token-1"}
+	notConverged := func() error {
+		return fmt.Errorf("%w for %s: ns2.example.com:53 does not serve the record yet", ErrNotConverged, rec.FQDN)
+	}
+
+	// The error is returned until the timeout has passed
+	if err := tracker.wait(rec, 50*time.Millisecond, notConverged); !errors.Is(err, ErrNotConverged) {
+		t.Fatalf("want ErrNotConverged, got %v", err)
+	}
+
+	// After the timeout, the error is terminal and names the
+	// lagging nameservers
+	time.Sleep(50 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		err := tracker.wait(rec, 50*time.Millisecond, notConverged)
+		if !errors.Is(err, ErrConvergenceTimeout) || errors.Is(err, ErrNotConverged) {
+			t.Fatalf("want ErrConvergenceTimeout only, got %v", err)
+		}
+
+		if !strings.Contains(err.Error(), "ns2.example.com:53") {
+			t.Errorf("want lagging nameserver in the error, got %s", err)
+		}
+	}
+
+	// The nameservers may still converge after the timeout
+	if err := tracker.wait(rec, 50*time.Millisecond, func() error { return nil }); err != nil {
+		t.Fatalf("want converged, got %s", err)
+	}
+
+	// Waiting starts anew for the next challenge
+	if err := tracker.wait(rec, 50*time.Millisecond, notConverged); !errors.Is(err, ErrNotConverged) {
+		t.Fatalf("want ErrNotConverged, got %v", err)
+	}
+
+	tracker.done(rec)
+	if len(tracker.started) != 0 {
+		t.Errorf("want no records left, got %d", len(tracker.started))
+	}
+}
@@ -0,0 +1,70 @@
+package bind
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// MemoryUpdater is an Updater, which keeps the TXT records in
+// memory.  It is meant to be used in tests, where no DNS server is
+// available.
+type MemoryUpdater struct {
+	mu      sync.Mutex
+	records map[string][]string
+}
+
+// NewMemoryUpdater creates a new, empty MemoryUpdater.
+func NewMemoryUpdater() *MemoryUpdater {
+	m := &MemoryUpdater{
+		records: make(map[string][]string),
+	}
+
+	return m
+}
+
+// AddTXT implements the Updater interface
+func (m *MemoryUpdater) AddTXT(ctx context.Context, rec ChallengeRecord) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name := dns.CanonicalName(rec.FQDN)
+	if slices.Contains(m.records[name], rec.Value) {
+		return Result{}, nil
+	}
+	m.records[name] = append(m.records[name], rec.Value)
+
+	return Result{Changed: true}, nil
+}
+
+// RemoveTXT implements the Updater interface
+func (m *MemoryUpdater) RemoveTXT(ctx context.Context, rec ChallengeRecord) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name := dns.CanonicalName(rec.FQDN)
+	if !slices.Contains(m.records[name], rec.Value) {
+		return Result{}, nil
+	}
+
+	m.records[name] = slices.DeleteFunc(m.records[name], func(v string) bool {
+		return v == rec.Value
+	})
+
+	if len(m.records[name]) == 0 {
+		delete(m.records, name)
+	}
+
+	return Result{Changed: true}, nil
+}
+
+// Records returns the values of the TXT records with the given
+// name.
+func (m *MemoryUpdater) Records(fqdn string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.records[dns.CanonicalName(fqdn)])
+}
@@ -0,0 +1,47 @@
+package bind
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestMemoryUpdater(t *testing.T) {
+	m := NewMemoryUpdater()
+	ctx := context.Background()
+	fqdn := "_acme-challenge.example.com."
+	rec1 := ChallengeRecord{Zone: "example.com.", FQDN: fqdn, TTL: 300, Value: "token-1"}
+	rec2 := ChallengeRecord{Zone: "example.com.", FQDN: fqdn, TTL: 300, Value: "token-2"}
+
+	for i, rec := range []ChallengeRecord{rec1, rec2, rec1} {
+		result, err := m.AddTXT(ctx, rec)
+		if err != nil {
+			t.Fatalf("failed to add record: %s", err)
+		}
+
+		if want := i < 2; result.Changed != want {
+			t.Errorf("%d: want changed %t, got %t", i, want, result.Changed)
+		}
+	}
+
+	if got := m.Records(fqdn); !slices.Equal(got, []string{"token-1", "token-2"}) {
+		t.Fatalf("want [token-1 token-2], got %v", got)
+	}
+
+	if _, err := m.RemoveTXT(ctx, rec1); err != nil {
+		t.Fatalf("failed to remove record: %s", err)
+	}
+
+	if got := m.Records(fqdn); !slices.Equal(got, []string{"token-2"}) {
+		t.Fatalf("want [token-2], got %v", got)
+	}
+
+	result, err := m.RemoveTXT(ctx, rec1)
+	if err != nil {
+		t.Fatalf("failed to remove record: %s", err)
+	}
+
+	if result.Changed {
+		t.Fatal("want no change when removing a missing record")
+	}
+}
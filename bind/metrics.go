@@ -0,0 +1,104 @@
+package bind
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// metricsSubsystem is the subsystem of the metrics exported by the
+// solver.
+const metricsSubsystem = "bind9_webhook"
+
+// tsigClockSkew is the clock skew between the webhook and the
+// nameservers, as measured from TSIG BADTIME responses.
+var tsigClockSkew = metrics.NewGaugeVec(
+	&metrics.GaugeOpts{
+		Subsystem:      metricsSubsystem,
+		Name:           "tsig_clock_skew_seconds",
+		Help:           "Clock skew between the nameserver and the webhook in seconds, as measured from the last TSIG BADTIME response of the nameserver.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"server"},
+)
+
+// circuitBreakerState is the state of the circuit breakers of the
+// nameservers.
+var circuitBreakerState = metrics.NewGaugeVec(
+	&metrics.GaugeOpts{
+		Subsystem:      metricsSubsystem,
+		Name:           "circuit_breaker_state",
+		Help:           "State of the circuit breaker of the nameserver, i.e. 0 (closed), 1 (open) or 2 (half-open).",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"server"},
+)
+
+// updateRetries is the number of retried updates.
+var updateRetries = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Subsystem:      metricsSubsystem,
+		Name:           "update_retries_total",
+		Help:           "Number of updates retried after a transient failure of the nameserver.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"server"},
+)
+
+// notifyFailures is the number of NOTIFY messages, which were not
+// acknowledged.
+var notifyFailures = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Subsystem:      metricsSubsystem,
+		Name:           "notify_failures_total",
+		Help:           "Number of NOTIFY messages, which the server did not acknowledge.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"server"},
+)
+
+// discoveryCacheLookups is the number of lookups of the discovered
+// nameservers of a zone in the discovery cache.
+var discoveryCacheLookups = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Subsystem:      metricsSubsystem,
+		Name:           "discovery_cache_lookups_total",
+		Help:           "Number of lookups of the discovered nameservers of a zone in the discovery cache, by result, i.e. hit or miss.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"result"},
+)
+
+// discoveryCacheEntries is the number of zones, whose discovered
+// nameservers are cached.
+var discoveryCacheEntries = metrics.NewDesc(
+	metrics.BuildFQName("", metricsSubsystem, "discovery_cache_entries"),
+	"Number of zones, whose discovered nameservers or failed discovery are cached.",
+	nil, nil, metrics.ALPHA, "",
+)
+
+// discoveryCacheCollector reports the number of cached discoveries,
+// which did not expire yet, when the metrics are collected.
+type discoveryCacheCollector struct {
+	metrics.BaseStableCollector
+}
+
+// DescribeWithStability implements the metrics.StableCollector
+// interface
+func (c *discoveryCacheCollector) DescribeWithStability(ch chan<- *metrics.Desc) {
+	ch <- discoveryCacheEntries
+}
+
+// CollectWithStability implements the metrics.StableCollector
+// interface
+func (c *discoveryCacheCollector) CollectWithStability(ch chan<- metrics.Metric) {
+	ch <- metrics.NewLazyConstMetric(discoveryCacheEntries, metrics.GaugeValue, float64(cachedDiscoveries()))
+}
+
+func init() {
+	legacyregistry.MustRegister(tsigClockSkew)
+	legacyregistry.MustRegister(circuitBreakerState)
+	legacyregistry.MustRegister(updateRetries)
+	legacyregistry.MustRegister(notifyFailures)
+	legacyregistry.MustRegister(discoveryCacheLookups)
+	legacyregistry.CustomMustRegister(&discoveryCacheCollector{})
+}
@@ -0,0 +1,59 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/miekg/dns"
+	"k8s.io/klog/v2"
+)
+
+// ErrNotifyFailed is returned when a server did not acknowledge a
+// NOTIFY message.
+var ErrNotifyFailed = errors.New("notify failed")
+
+// notify sends a NOTIFY message for the zone to each of the servers
+// in parallel, so that they transfer the updated zone right away,
+// instead of waiting for their refresh timer.  Failures do not fail
+// the update, but are logged and counted by the notify_failures_total
+// metric.  Returns the errors of the servers, which failed.
+func (c *rfc2136Client) notify(ctx context.Context, zone string, servers []endpoint) error {
+	// NOTIFY messages are sent over UDP and TCP to the standard
+	// port of the servers, and are not signed, since the
+	// servers may not know the key used for the updates.
+	tr := *c.transport
+	tr.Net = TransportAuto
+	tr.TSIG = nil
+	tr.SIG0 = nil
+	tr.TLSConfig = nil
+	tr.SourceAddr = withoutPort(tr.SourceAddr)
+
+	errs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		go func(i int, server endpoint) {
+			defer wg.Done()
+
+			msg := new(dns.Msg)
+			msg.SetNotify(zone)
+			resp, err := tr.exchangeEndpoint(ctx, msg, server)
+			switch {
+			case err != nil:
+				errs[i] = fmt.Errorf("%w for zone %s to %s: %w", ErrNotifyFailed, zone, server, err)
+			case resp.Rcode != dns.RcodeSuccess:
+				errs[i] = fmt.Errorf("%w for zone %s to %s: %s", ErrNotifyFailed, zone, server, dns.RcodeToString[resp.Rcode])
+			default:
+				return
+			}
+
+			klog.InfoS("failed to notify server", "zone", zone, "server", server, "err", errs[i])
+			notifyFailures.WithLabelValues(server.String()).Inc()
+		}(i, server)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
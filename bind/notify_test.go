@@ -0,0 +1,72 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestRFC2136ClientNotify(t *testing.T) {
+	primary := newTestServer(t)
+	secondary := newTestServer(t)
+
+	// A server, which is not listening
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	down := pc.LocalAddr().String()
+	pc.Close()
+
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	client := newRFC2136Client(key)
+	client.nameservers = []string{primary.Addr}
+	for _, server := range []string{secondary.Addr, down} {
+		ep, err := parseEndpoint(server, DefaultUpdatePort)
+		if err != nil {
+			t.Fatalf("failed to parse endpoint: %s", err)
+		}
+		client.notifyServers = append(client.notifyServers, ep)
+	}
+
+	// A failed notification does not fail the update
+	ctx := context.Background()
+	rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}
+	if _, err := client.AddTXT(ctx, rec); err != nil {
+		t.Fatalf("failed to add record: %s", err)
+	}
+
+	if got := secondary.Notifies(); !slices.Equal(got, []string{"example.com."}) {
+		t.Errorf("want NOTIFY for [example.com.], got %v", got)
+	}
+
+	failures, err := testutil.GetCounterMetricValue(notifyFailures.WithLabelValues(down))
+	if err != nil {
+		t.Fatalf("failed to get metric: %s", err)
+	}
+
+	if failures != 1 {
+		t.Errorf("want 1 notify failure, got %f", failures)
+	}
+
+	// No notifications are sent, if nothing changed
+	if _, err := client.AddTXT(ctx, rec); err != nil {
+		t.Fatalf("failed to add record: %s", err)
+	}
+
+	if got := secondary.Notifies(); len(got) != 1 {
+		t.Errorf("want 1 NOTIFY, got %d", len(got))
+	}
+
+	if err := client.notify(ctx, "example.com.", client.notifyServers); !errors.Is(err, ErrNotifyFailed) {
+		t.Errorf("want ErrNotifyFailed, got %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// ErrLookupFailed is returned when none of the resolvers answered a
+// lookup.
+var ErrLookupFailed = errors.New("lookup failed")
+
+// DefaultResolvConf is the resolver configuration used to discover
+// the nameservers, unless the resolvers are specified.
+const DefaultResolvConf = "/etc/resolv.conf"
+
+// DefaultLookupTimeout is the timeout for a single lookup.
+const DefaultLookupTimeout = 5 * time.Second
+
+// resolver sends recursive queries to its servers, in order to look
+// up the records used to discover the nameservers of a zone.
+type resolver struct {
+	// Servers are the addresses of the recursive resolvers, i.e.
+	// host:port, which are tried in order
+	Servers []string
+
+	// URL is the endpoint of the DNS-over-HTTPS resolver, which
+	// is used instead of the servers, if set
+	URL string
+
+	// HTTPClient is used to send the DNS-over-HTTPS queries.  If
+	// nil, the default HTTP client is used.
+	HTTPClient *http.Client
+
+	// Timeout is the timeout for a single query
+	Timeout time.Duration
+}
+
+// newResolver creates a new resolver, which sends the queries to the
+// given servers, i.e. host, host:port or [v6]:port.  If no port is
+// given, port 53 is used.
+func newResolver(servers []string) (*resolver, error) {
+	r := &resolver{Timeout: DefaultLookupTimeout}
+	for _, server := range servers {
+		ep, err := parseEndpoint(server, DefaultUpdatePort)
+		if err != nil {
+			return nil, err
+		}
+		r.Servers = append(r.Servers, ep.String())
+	}
+
+	return r, nil
+}
+
+// systemResolver returns the resolver configured in
+// DefaultResolvConf.
+var systemResolver = sync.OnceValues(func() (*resolver, error) {
+	conf, err := dns.ClientConfigFromFile(DefaultResolvConf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", DefaultResolvConf, err)
+	}
+
+	r := &resolver{Timeout: DefaultLookupTimeout}
+	for _, server := range conf.Servers {
+		r.Servers = append(r.Servers, net.JoinHostPort(server, conf.Port))
+	}
+
+	return r, nil
+})
+
+// lookup looks up the records of the given name and type, and
+// returns the records of the answer section, which match the name
+// and type.  A name without records of the type is not an error.
+func (r *resolver) lookup(ctx context.Context, name string, qtype uint16) ([]dns.RR, error) {
+	name = dns.Fqdn(name)
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, qtype)
+	msg.SetEdns0(dns.DefaultMsgSize, false)
+
+	servers := r.Servers
+	if r.URL != "" {
+		servers = []string{r.URL}
+	}
+
+	var errs []error
+	for _, server := range servers {
+		resp, err := r.exchange(ctx, msg, server)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		switch resp.Rcode {
+		case dns.RcodeSuccess, dns.RcodeNameError:
+		default:
+			errs = append(errs, fmt.Errorf("%s responded with %s", server, dns.RcodeToString[resp.Rcode]))
+			continue
+		}
+
+		var rrs []dns.RR
+		for _, rr := range resp.Answer {
+			if rr.Header().Rrtype == qtype && dns.CanonicalName(rr.Header().Name) == dns.CanonicalName(name) {
+				rrs = append(rrs, rr)
+			}
+		}
+
+		return rrs, nil
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("%w for %s %s: no resolvers configured", ErrLookupFailed, name, dns.TypeToString[qtype])
+	}
+
+	return nil, fmt.Errorf("%w for %s %s: %w", ErrLookupFailed, name, dns.TypeToString[qtype], errors.Join(errs...))
+}
+
+// exchange sends the query to the server over UDP, and re-sends it
+// over TCP, if the response was truncated.  Queries to a
+// DNS-over-HTTPS resolver are sent over HTTPS instead.
+func (r *resolver) exchange(ctx context.Context, msg *dns.Msg, server string) (*dns.Msg, error) {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultLookupTimeout
+	}
+
+	if r.URL != "" {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return r.exchangeDoH(ctx, msg)
+	}
+
+	client := &dns.Client{Net: "udp", Timeout: timeout}
+	resp, _, err := client.ExchangeContext(ctx, msg, server)
+	if err != nil || !resp.Truncated {
+		return resp, err
+	}
+
+	client.Net = "tcp"
+	resp, _, err = client.ExchangeContext(ctx, msg, server)
+
+	return resp, err
+}
+
+// lookupNetIP looks up the IPv6 and IPv4 addresses of the host.
+func (r *resolver) lookupNetIP(ctx context.Context, host string) ([]netip.Addr, error) {
+	var addrs []netip.Addr
+	var errs []error
+	for _, qtype := range []uint16{dns.TypeAAAA, dns.TypeA} {
+		rrs, err := r.lookup(ctx, host, qtype)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, rr := range rrs {
+			var ip net.IP
+			switch rr := rr.(type) {
+			case *dns.AAAA:
+				ip = rr.AAAA
+			case *dns.A:
+				ip = rr.A
+			}
+			if addr, ok := netip.AddrFromSlice(ip); ok {
+				addrs = append(addrs, addr.Unmap())
+			}
+		}
+	}
+
+	if len(addrs) == 0 {
+		if err := errors.Join(errs...); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w for %s: no addresses found", ErrLookupFailed, host)
+	}
+
+	return addrs, nil
+}
+
+// discoverNameservers discovers the nameservers of the zone, which
+// accept dynamic updates, as described in RFC 2136, section 4.  The
+// primary nameserver from the MNAME field of the SOA record comes
+// first, followed by the other nameservers from the NS records of
+// the zone, which are used in case the primary refuses the update.
+// Returns the lowest TTL of the records as well.
+func discoverNameservers(ctx context.Context, r *resolver, zone, port string) ([]endpoint, time.Duration, error) {
+	var hosts []string
+	soa, soaErr := r.lookup(ctx, zone, dns.TypeSOA)
+	if soaErr == nil && len(soa) > 0 {
+		hosts = append(hosts, soa[0].(*dns.SOA).Ns)
+	}
+
+	ns, nsErr := r.lookup(ctx, zone, dns.TypeNS)
+	for _, rr := range ns {
+		host := rr.(*dns.NS).Ns
+		if !containsName(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+
+	if len(hosts) == 0 {
+		if err := errors.Join(soaErr, nsErr); err != nil {
+			return nil, 0, fmt.Errorf("failed to discover nameservers of %s: %w", zone, err)
+		}
+		return nil, 0, fmt.Errorf("%w for %s", ErrNoNameserverFound, zone)
+	}
+
+	endpoints := make([]endpoint, 0, len(hosts))
+	for _, host := range hosts {
+		endpoints = append(endpoints, endpoint{Host: strings.TrimSuffix(host, "."), Port: port})
+	}
+
+	return endpoints, minTTL(append(soa, ns...)), nil
+}
+
+// minTTL returns the lowest TTL of the records.
+func minTTL(rrs []dns.RR) time.Duration {
+	var ttl uint32
+	for i, rr := range rrs {
+		if i == 0 || rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+		}
+	}
+
+	return time.Duration(ttl) * time.Second
+}
+
+// containsName returns true, if the domain names contain the given
+// name, ignoring case.
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if dns.CanonicalName(n) == dns.CanonicalName(name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,161 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestDiscoverNameservers(t *testing.T) {
+	testCases := []struct {
+		name    string
+		records []string
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "primary first",
+			records: []string{
+				"example.com. 3600 IN SOA ns0.example.com. hostmaster.example.com. 1 7200 3600 1209600 300",
+				"example.com. 3600 IN NS ns1.example.com.",
+				"example.com. 3600 IN NS NS0.example.com.",
+				"example.com. 3600 IN NS ns2.example.com.",
+			},
+			want: []string{"ns0.example.com", "ns1.example.com", "ns2.example.com"},
+		},
+		{
+			name: "hidden primary",
+			records: []string{
+				"example.com. 3600 IN SOA hidden.example.com. hostmaster.example.com. 1 7200 3600 1209600 300",
+				"example.com. 3600 IN NS ns1.example.com.",
+			},
+			want: []string{"hidden.example.com", "ns1.example.com"},
+		},
+		{
+			name: "no SOA",
+			records: []string{
+				"example.com. 3600 IN NS ns1.example.com.",
+			},
+			want: []string{"ns1.example.com"},
+		},
+		{
+			name:    "no records",
+			wantErr: ErrNoNameserverFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		ts := newTestServer(t)
+		ts.AddRecords(t, tc.records...)
+
+		r := &resolver{Servers: []string{ts.Addr}}
+		got, _, err := discoverNameservers(context.Background(), r, "example.com.", "53")
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: want error %v, got %v", tc.name, tc.wantErr, err)
+			continue
+		}
+
+		var hosts []string
+		for _, ep := range got {
+			hosts = append(hosts, ep.Host)
+			if ep.Port != "53" {
+				t.Errorf("%s: want port 53, got %s", tc.name, ep.Port)
+			}
+		}
+
+		if !slices.Equal(hosts, tc.want) {
+			t.Errorf("%s: want %v, got %v", tc.name, tc.want, hosts)
+		}
+	}
+}
+
+func TestResolverLookupFailed(t *testing.T) {
+	// A resolver, which is not listening
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	down := pc.LocalAddr().String()
+	pc.Close()
+
+	ts := newTestServer(t)
+	ts.AddRecords(t, "example.com. 3600 IN NS ns1.example.com.")
+
+	// The next resolver is tried, if one fails
+	r := &resolver{Servers: []string{down, ts.Addr}}
+	rrs, err := r.lookup(context.Background(), "example.com.", dns.TypeNS)
+	if err != nil || len(rrs) != 1 {
+		t.Fatalf("want 1 record, got %v (%v)", rrs, err)
+	}
+
+	r = &resolver{Servers: []string{down}}
+	if _, err := r.lookup(context.Background(), "example.com.", dns.TypeNS); !errors.Is(err, ErrLookupFailed) {
+		t.Fatalf("want ErrLookupFailed, got %v", err)
+	}
+}
+
+func TestRFC2136ClientDiscovery(t *testing.T) {
+	testCases := []struct {
+		name        string
+		rcode       int
+		wantServer  int
+		wantUpdates []int
+	}{
+		{name: "primary accepts", rcode: dns.RcodeSuccess, wantServer: 0, wantUpdates: []int{1, 0}},
+		{name: "primary refuses", rcode: dns.RcodeRefused, wantServer: 1, wantUpdates: []int{0, 1}},
+		{name: "primary not authoritative", rcode: dns.RcodeNotAuth, wantServer: 1, wantUpdates: []int{0, 1}},
+	}
+
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	for _, tc := range testCases {
+		// The primary and the secondary listen on the same
+		// port of different loopback addresses.
+		primary := newTestServer(t)
+		_, port, _ := net.SplitHostPort(primary.Addr)
+		secondary := newTestServerAddr(t, net.JoinHostPort("127.0.0.2", port))
+		servers := []*testServer{primary, secondary}
+
+		primary.Respond(tc.rcode)
+		primary.AddRecords(t,
+			"example.com. 3600 IN SOA ns0.example.com. hostmaster.example.com. 1 7200 3600 1209600 300",
+			"example.com. 3600 IN NS ns1.example.com.",
+			"ns0.example.com. 3600 IN A 127.0.0.1",
+			"ns1.example.com. 3600 IN A 127.0.0.2",
+		)
+
+		r := &resolver{Servers: []string{primary.Addr}}
+		client := newRFC2136Client(key)
+		client.resolver = r
+		client.transport.Resolver = r
+		client.transport.Net = TransportUDP
+
+		nameservers, err := findNameservers(context.Background(), r, "example.com.", port, DiscoverySOA, TransportUDP)
+		if err != nil {
+			t.Fatalf("%s: failed to discover nameservers: %s", tc.name, err)
+		}
+
+		rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}
+		results, err := client.applyAny(context.Background(), nameservers, rec.Zone, []change{{Add: true, Rec: rec}})
+		if err != nil {
+			t.Fatalf("%s: failed to add record: %s", tc.name, err)
+		}
+
+		if want := nameservers[tc.wantServer].String(); results[0].Server != want {
+			t.Errorf("%s: want update applied by %s, got %s", tc.name, want, results[0].Server)
+		}
+
+		for i, ts := range servers {
+			if ts.Updates() != tc.wantUpdates[i] {
+				t.Errorf("%s: want %d updates applied by %s, got %d", tc.name, tc.wantUpdates[i], ts.Addr, ts.Updates())
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+)
+
+// DefaultUpdateAttempts is the default number of attempts made to
+// send an update, unless specified in the configuration.
+const DefaultUpdateAttempts = 4
+
+// DefaultRetryBackoff is the default time to wait before the first
+// retry, unless specified in the configuration.  The time doubles
+// with each retry, up to maxRetryBackoff.
+const DefaultRetryBackoff = 500 * time.Millisecond
+
+// maxRetryBackoff is the maximum time to wait between two attempts.
+const maxRetryBackoff = 10 * time.Second
+
+// retryPolicy controls how failed updates are retried.
+type retryPolicy struct {
+	// Attempts is the maximum number of attempts, including the
+	// first one
+	Attempts int
+
+	// Backoff is the time to wait before the first retry
+	Backoff time.Duration
+}
+
+// backoff returns the time to wait before the given retry, starting
+// at zero.  The time grows exponentially, and is randomized to
+// between half and the full time, so that clients do not retry in
+// lockstep.
+func (p retryPolicy) backoff(retry int) time.Duration {
+	d := p.Backoff
+	for i := 0; i < retry && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, maxRetryBackoff)
+
+	if d <= 1 {
+		return d
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+// isRetryable returns true, if the error is likely transient, i.e.
+// a network error or a SERVFAIL response.  Updates rejected by the
+// nameserver, e.g. with REFUSED or NOTAUTH, fail the same way when
+// retried.
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var updateErr *UpdateError
+	if errors.As(err, &updateErr) {
+		return errors.Is(updateErr.Err, ErrServFail)
+	}
+
+	if errors.Is(err, ErrResponseNotSigned) || errors.Is(err, ErrResponseVerification) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+
+	// The source address is in use locally, which is not a
+	// failure of the nameserver
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// sleep waits for the given duration, or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,112 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestIsRetryable(t *testing.T) {
+	testCases := []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: &UpdateError{Err: ErrServFail}, want: true},
+		{err: &UpdateError{Err: ErrRefused}, want: false},
+		{err: &UpdateError{Err: ErrNotAuth}, want: false},
+		{err: &UpdateError{Err: ErrBadTime}, want: false},
+		{err: fmt.Errorf("failed to send update: %w", &net.OpError{Op: "read", Err: errors.New("i/o timeout")}), want: true},
+		{err: fmt.Errorf("failed to send update: %w", io.EOF), want: true},
+		{err: errors.Join(io.EOF, &net.OpError{Op: "dial"}), want: true},
+		{err: fmt.Errorf("%w by 127.0.0.1:53", ErrResponseNotSigned), want: false},
+		{err: fmt.Errorf("%w for 127.0.0.1:53", ErrCircuitOpen), want: false},
+		{err: &net.OpError{Op: "dial", Err: os.NewSyscallError("bind", syscall.EADDRINUSE)}, want: false},
+		{err: ErrNoNameserverFound, want: false},
+	}
+
+	for _, tc := range testCases {
+		if got := isRetryable(tc.err); got != tc.want {
+			t.Errorf("%v: want retryable %t, got %t", tc.err, tc.want, got)
+		}
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := retryPolicy{Attempts: 10, Backoff: time.Second}
+
+	for retry, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, maxRetryBackoff, maxRetryBackoff} {
+		for i := 0; i < 100; i++ {
+			if got := p.backoff(retry); got < want/2 || got >= want {
+				t.Fatalf("retry %d: want backoff in [%s, %s), got %s", retry, want/2, want, got)
+			}
+		}
+	}
+}
+
+func TestRFC2136ClientRetry(t *testing.T) {
+	testCases := []struct {
+		name      string
+		setup     func(ts *testServer)
+		wantErr   error
+		wantTries int
+	}{
+		{
+			name:      "transient SERVFAIL",
+			setup:     func(ts *testServer) { ts.FailUpdates(2, dns.RcodeServerFailure) },
+			wantTries: 3,
+		},
+		{
+			name:      "dropped update",
+			setup:     func(ts *testServer) { ts.DropUpdates(1) },
+			wantTries: 2,
+		},
+		{
+			name:      "persistent SERVFAIL",
+			setup:     func(ts *testServer) { ts.FailUpdates(10, dns.RcodeServerFailure) },
+			wantErr:   ErrServFail,
+			wantTries: 3,
+		},
+		{
+			name:      "REFUSED",
+			setup:     func(ts *testServer) { ts.FailUpdates(10, dns.RcodeRefused) },
+			wantErr:   ErrRefused,
+			wantTries: 1,
+		},
+	}
+
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	for _, tc := range testCases {
+		ts := newTestServer(t)
+		tc.setup(ts)
+
+		client := newRFC2136Client(key)
+		client.nameservers = []string{ts.Addr}
+		client.transport.Net = TransportUDP
+		client.transport.ReadTimeout = 100 * time.Millisecond
+		client.retry = retryPolicy{Attempts: 3, Backoff: time.Millisecond}
+
+		rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}
+		_, err := client.AddTXT(context.Background(), rec)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: want error %v, got %v", tc.name, tc.wantErr, err)
+		}
+
+		// One query, followed by the attempts to update
+		if got := ts.Requests("udp") - 1; got != tc.wantTries {
+			t.Errorf("%s: want %d attempts, got %d", tc.name, tc.wantTries, got)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"k8s.io/klog/v2"
+)
+
+// ErrNoNameserverFound is returned when no nameserver could be
+// found to send the dynamic updates to.
+var ErrNoNameserverFound = errors.New("no nameserver found")
+
+// ErrInvalidUpdateLease is returned when the update lease is not
+// between one second and the maximum lease of 2^32-1 seconds.
+var ErrInvalidUpdateLease = errors.New("invalid update lease")
+
+// DefaultUpdatePort is the port on which dynamic updates are sent,
+// unless the nameserver specifies a different one.
+const DefaultUpdatePort = "53"
+
+// DefaultTLSPort is the port on which dynamic updates are sent when
+// using the tls transport, unless the nameserver specifies a
+// different one.
+const DefaultTLSPort = "853"
+
+// DefaultTSIGFudge is the default time window in seconds, within
+// which a TSIG signature is considered valid.
+const DefaultTSIGFudge = 300
+
+// rfc2136Client sends TSIG or SIG(0) signed RFC 2136 dynamic
+// updates to a nameserver.
+type rfc2136Client struct {
+	// key is the TSIG key used to sign the updates, if not
+	// using SIG(0)
+	key *tsigKey
+
+	// transport is used to exchange messages with the
+	// nameserver
+	transport *transport
+
+	// nameservers are the endpoints of the nameservers to send
+	// the updates to, i.e. host, host:port or [v6]:port.  If
+	// empty, the nameserver is looked up for each zone.
+	nameservers []string
+
+	// retry controls how failed updates are retried
+	retry retryPolicy
+
+	// resolver is used to discover the nameservers.  If nil,
+	// the resolver of the system is used.
+	resolver *resolver
+
+	// lease is the time after which the nameserver removes the
+	// added records on its own.  If zero, the records are kept
+	// until removed.
+	lease time.Duration
+
+	// notifyServers are sent a NOTIFY message after each
+	// successful update
+	notifyServers []endpoint
+
+	// discovery is the mode in which the nameservers are
+	// discovered, if not specified
+	discovery string
+
+	// failover is the mode in which the nameservers are tried,
+	// i.e. in order or all at once
+	failover string
+
+	// serverTimeout is the time within which a nameserver must
+	// apply an update, before it is given up on.  If zero, there
+	// is no deadline.
+	serverTimeout time.Duration
+
+	// tlsFingerprint identifies the CA bundle, client certificate
+	// and key, which the TLS configuration of the transport was
+	// created from, since they cannot be compared once parsed
+	tlsFingerprint string
+}
+
+// newRFC2136Client creates a new client, which signs the updates
+// using the given TSIG key.
+func newRFC2136Client(key *tsigKey) *rfc2136Client {
+	c := &rfc2136Client{
+		key:           key,
+		transport:     newTransport(),
+		retry:         retryPolicy{Attempts: DefaultUpdateAttempts, Backoff: DefaultRetryBackoff},
+		failover:      DefaultFailover,
+		serverTimeout: DefaultNameserverTimeout,
+	}
+	c.transport.TSIG = key
+
+	return c
+}
+
+// newSIG0RFC2136Client creates a new client, which signs the updates
+// using the given SIG(0) key.
+func newSIG0RFC2136Client(key *sig0Key) *rfc2136Client {
+	c := &rfc2136Client{
+		transport:     newTransport(),
+		retry:         retryPolicy{Attempts: DefaultUpdateAttempts, Backoff: DefaultRetryBackoff},
+		failover:      DefaultFailover,
+		serverTimeout: DefaultNameserverTimeout,
+	}
+	c.transport.SIG0 = key
+
+	return c
+}
+
+// change is the addition or removal of a TXT record.
+type change struct {
+	// Add is true for additions and false for removals
+	Add bool
+
+	// Rec is the TXT record to add or remove
+	Rec ChallengeRecord
+}
+
+// AddTXT implements the Updater interface
+func (c *rfc2136Client) AddTXT(ctx context.Context, rec ChallengeRecord) (Result, error) {
+	results, err := c.apply(ctx, rec.Zone, []change{{Add: true, Rec: rec}})
+	if err != nil {
+		return Result{}, err
+	}
+
+	return results[0], nil
+}
+
+// RemoveTXT implements the Updater interface
+func (c *rfc2136Client) RemoveTXT(ctx context.Context, rec ChallengeRecord) (Result, error) {
+	results, err := c.apply(ctx, rec.Zone, []change{{Add: false, Rec: rec}})
+	if err != nil {
+		return Result{}, err
+	}
+
+	return results[0], nil
+}
+
+// apply applies the changes to the zone in a single UPDATE message,
+// and returns the result of each change.
+func (c *rfc2136Client) apply(ctx context.Context, zone string, changes []change) ([]Result, error) {
+	nameservers, err := c.findNameservers(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.applyAny(ctx, nameservers, zone, changes)
+}
+
+// applyTo applies the changes to the zone on the given nameserver.
+// The nameserver is queried for the records first, so that changes,
+// which are already in effect, are skipped and repeated calls do not
+// send another update.  RFC 2136 prerequisites cannot express this,
+// since value-dependent prerequisites compare whole RRsets, which may
+// hold the values of other challenges for the same name.
+func (c *rfc2136Client) applyTo(ctx context.Context, nameserver endpoint, zone string, changes []change) ([]Result, error) {
+	var err error
+
+	// Do not wait for a nameserver, which is known to be down
+	if err := breakerFor(nameserver.String()).check(); err != nil {
+		return nil, err
+	}
+
+	// The state of the records, as the changes are applied in
+	// order.  Records, which could not be queried, are missing.
+	type record struct{ fqdn, value string }
+	state := make(map[record]bool)
+
+	msg := new(dns.Msg)
+	msg.SetUpdate(zone)
+	results := make([]Result, len(changes))
+	for i, ch := range changes {
+		results[i].Server = nameserver.String()
+
+		key := record{fqdn: dns.CanonicalName(ch.Rec.FQDN), value: ch.Rec.Value}
+		exists, known := state[key]
+		if !known {
+			exists, err = c.hasTXT(ctx, nameserver, ch.Rec)
+			if err != nil {
+				klog.InfoS("failed to query TXT record, sending update", "fqdn", ch.Rec.FQDN, "server", nameserver, "err", err)
+			} else {
+				known = true
+			}
+		}
+
+		if known && exists == ch.Add {
+			continue
+		}
+
+		if ch.Add {
+			msg.Insert([]dns.RR{newTXT(ch.Rec.FQDN, ch.Rec.TTL, ch.Rec.Value)})
+		} else {
+			msg.Remove([]dns.RR{newTXT(ch.Rec.FQDN, 0, ch.Rec.Value)})
+		}
+		state[key] = ch.Add
+		results[i].Changed = true
+	}
+
+	if len(msg.Ns) == 0 {
+		return results, nil
+	}
+
+	if c.lease > 0 && hasAdditions(msg) {
+		setUpdateLease(msg, c.lease)
+	}
+
+	if err := c.update(ctx, nameserver, zone, msg); err != nil {
+		return nil, err
+	}
+
+	// Failed notifications are logged, and the secondaries
+	// eventually catch up on their own.
+	if len(c.notifyServers) > 0 {
+		c.notify(ctx, zone, c.notifyServers)
+	}
+
+	return results, nil
+}
+
+// findNameservers returns the endpoints of the nameservers, to which
+// the updates for the zone are sent, in order.  The configured
+// nameservers are used, if any.
+func (c *rfc2136Client) findNameservers(ctx context.Context, zone string) ([]endpoint, error) {
+	if len(c.nameservers) > 0 {
+		endpoints := make([]endpoint, 0, len(c.nameservers))
+		for _, ns := range c.nameservers {
+			ep, err := parseEndpoint(ns, c.transport.port())
+			if err != nil {
+				return nil, err
+			}
+			endpoints = append(endpoints, ep)
+		}
+		return endpoints, nil
+	}
+
+	r := c.resolver
+	if r == nil {
+		var err error
+		if r, err = systemResolver(); err != nil {
+			return nil, err
+		}
+	}
+
+	return findNameservers(ctx, r, zone, c.transport.port(), c.discovery, c.transport.Net)
+}
+
+// hasTXT queries the nameserver for the TXT record and returns true,
+// if the record exists.
+func (c *rfc2136Client) hasTXT(ctx context.Context, nameserver endpoint, rec ChallengeRecord) (bool, error) {
+	fqdn := dns.Fqdn(rec.FQDN)
+	msg := new(dns.Msg)
+	msg.SetQuestion(fqdn, dns.TypeTXT)
+	msg.RecursionDesired = false
+	c.sign(msg)
+
+	resp, err := c.transport.exchangeEndpoint(ctx, msg, nameserver)
+	if err := checkResponse(msg, resp, err, nameserver.String(), rec.Zone, c.keyName()); err != nil {
+		var updateErr *UpdateError
+		if errors.As(err, &updateErr) && updateErr.Rcode == dns.RcodeNameError {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if !resp.Authoritative {
+		return false, fmt.Errorf("response of %s for %s is not authoritative", nameserver, fqdn)
+	}
+
+	for _, rr := range resp.Answer {
+		txt, ok := rr.(*dns.TXT)
+		if ok && dns.CanonicalName(txt.Hdr.Name) == dns.CanonicalName(fqdn) && strings.Join(txt.Txt, "") == rec.Value {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// update signs and sends the UPDATE message to the nameserver.
+// Network errors and SERVFAIL responses are retried with exponential
+// backoff, and recorded by the circuit breaker of the nameserver.
+// Retrying is safe, since adding an existing record, or deleting a
+// missing one, is a no-op.
+func (c *rfc2136Client) update(ctx context.Context, nameserver endpoint, zone string, msg *dns.Msg) error {
+	server := nameserver.String()
+	breaker := breakerFor(server)
+
+	for attempt := 1; ; attempt++ {
+		if err := breaker.allow(); err != nil {
+			return err
+		}
+
+		// Each attempt is signed anew, so that its signature
+		// does not expire while backing off.
+		req := msg.Copy()
+		c.sign(req)
+		resp, err := c.transport.exchangeEndpoint(ctx, req, nameserver)
+		err = checkResponse(req, resp, err, server, zone, c.keyName())
+		if ctx.Err() != nil {
+			breaker.abort()
+			return err
+		}
+
+		retryable := isRetryable(err)
+		breaker.record(retryable)
+		if !retryable || attempt >= c.retry.Attempts {
+			return err
+		}
+
+		backoff := c.retry.backoff(attempt - 1)
+		klog.InfoS("retrying update", "zone", zone, "server", server, "attempt", attempt, "backoff", backoff, "err", err)
+		updateRetries.WithLabelValues(server).Inc()
+		if err := sleep(ctx, backoff); err != nil {
+			return err
+		}
+	}
+}
+
+// sign adds the TSIG record to the message, if using TSIG.  Messages
+// are signed with SIG(0) by the transport.
+func (c *rfc2136Client) sign(msg *dns.Msg) {
+	if c.key != nil {
+		msg.SetTsig(c.key.Name, c.key.Algorithm, c.key.Fudge, time.Now().Unix())
+	}
+}
+
+// keyName returns the name of the key used to sign the updates.
+func (c *rfc2136Client) keyName() string {
+	if c.key != nil {
+		return c.key.Name
+	}
+
+	if c.transport.SIG0 != nil {
+		return c.transport.SIG0.Key.Hdr.Name
+	}
+
+	return ""
+}
+
+// hasAdditions returns true, if the UPDATE message adds any records.
+func hasAdditions(msg *dns.Msg) bool {
+	for _, rr := range msg.Ns {
+		if rr.Header().Class == dns.ClassINET {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setUpdateLease attaches the EDNS0 Update Lease option to the UPDATE
+// message, which asks the nameserver to remove the added records
+// after the lease, as described in draft-ietf-dnssd-update-lease.
+// Nameservers, which do not support the option, ignore it.
+func setUpdateLease(msg *dns.Msg, lease time.Duration) {
+	msg.SetEdns0(dns.DefaultMsgSize, false)
+	opt := msg.IsEdns0()
+	opt.Option = append(opt.Option, &dns.EDNS0_UL{
+		Code:  dns.EDNS0UL,
+		Lease: uint32(lease / time.Second),
+	})
+}
+
+// newTXT creates a new TXT record with the given value.
+func newTXT(fqdn string, ttl int, value string) *dns.TXT {
+	rr := &dns.TXT{
+		Hdr: dns.RR_Header{
+			Name:   dns.Fqdn(fqdn),
+			Rrtype: dns.TypeTXT,
+			Class:  dns.ClassINET,
+			Ttl:    uint32(ttl),
+		},
+		Txt: []string{value},
+	}
+
+	return rr
+}
+
+// findNameservers returns the endpoints of the nameservers, to which
+// dynamic updates for the zone are sent on the given port, unless
+// specified otherwise.  If the $USE_NAMESERVER environment variable
+// is set, updates are always sent to it, otherwise the nameservers
+// of the zone are discovered using the resolver, either from its
+// SOA and NS records, or from its SRV records of the services
+// accepting updates over the network.
+func findNameservers(ctx context.Context, r *resolver, zone, port, discovery, network string) ([]endpoint, error) {
+	if ns := os.Getenv("USE_NAMESERVER"); ns != "" {
+		ep, err := parseEndpoint(ns, port)
+		if err != nil {
+			return nil, err
+		}
+		return []endpoint{ep}, nil
+	}
+
+	return discoverCached(ctx, r, zone, port, discovery, network)
+}
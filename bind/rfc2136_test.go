@@ -0,0 +1,187 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRFC2136ClientAddRemoveTXT(t *testing.T) {
+	ts := newTestServer(t)
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	client := newRFC2136Client(key)
+	client.nameservers = []string{ts.Addr}
+
+	ctx := context.Background()
+	fqdn := "_acme-challenge.example.com."
+	rec := ChallengeRecord{Zone: "example.com.", FQDN: fqdn, TTL: 300, Value: "token-1"}
+	if _, err := client.AddTXT(ctx, rec); err != nil {
+		t.Fatalf("failed to add TXT record: %s", err)
+	}
+
+	if got := ts.TXT(fqdn); !slices.Equal(got, []string{"token-1"}) {
+		t.Fatalf("want [token-1], got %v", got)
+	}
+
+	if _, err := client.RemoveTXT(ctx, rec); err != nil {
+		t.Fatalf("failed to remove TXT record: %s", err)
+	}
+
+	if got := ts.TXT(fqdn); len(got) != 0 {
+		t.Fatalf("want no records, got %v", got)
+	}
+}
+
+func TestRFC2136ClientIdempotent(t *testing.T) {
+	ts := newTestServer(t)
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	client := newRFC2136Client(key)
+	client.nameservers = []string{ts.Addr}
+
+	ctx := context.Background()
+	fqdn := "_acme-challenge.example.com."
+	rec1 := ChallengeRecord{Zone: "example.com.", FQDN: fqdn, TTL: 300, Value: "token-1"}
+	rec2 := ChallengeRecord{Zone: "example.com.", FQDN: fqdn, TTL: 300, Value: "token-2"}
+
+	testCases := []struct {
+		op          func(context.Context, ChallengeRecord) (Result, error)
+		rec         ChallengeRecord
+		wantChanged bool
+		wantUpdates int
+	}{
+		{op: client.AddTXT, rec: rec1, wantChanged: true, wantUpdates: 1},
+		{op: client.AddTXT, rec: rec1, wantChanged: false, wantUpdates: 1},
+		{op: client.AddTXT, rec: rec2, wantChanged: true, wantUpdates: 2},
+		{op: client.RemoveTXT, rec: rec1, wantChanged: true, wantUpdates: 3},
+		{op: client.RemoveTXT, rec: rec1, wantChanged: false, wantUpdates: 3},
+		{op: client.AddTXT, rec: rec2, wantChanged: false, wantUpdates: 3},
+	}
+
+	for i, tc := range testCases {
+		result, err := tc.op(ctx, tc.rec)
+		if err != nil {
+			t.Fatalf("%d: failed to update record: %s", i, err)
+		}
+
+		if result.Changed != tc.wantChanged || result.Server != ts.Addr {
+			t.Errorf("%d: want changed %t by %s, got %+v", i, tc.wantChanged, ts.Addr, result)
+		}
+
+		if ts.Updates() != tc.wantUpdates {
+			t.Errorf("%d: want %d updates, got %d", i, tc.wantUpdates, ts.Updates())
+		}
+	}
+
+	if got := ts.TXT(fqdn); !slices.Equal(got, []string{"token-2"}) {
+		t.Fatalf("want [token-2], got %v", got)
+	}
+}
+
+func TestRFC2136ClientUpdateLease(t *testing.T) {
+	ts := newTestServer(t)
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	client := newRFC2136Client(key)
+	client.nameservers = []string{ts.Addr}
+	client.lease = time.Hour
+
+	ctx := context.Background()
+	rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}
+	if _, err := client.AddTXT(ctx, rec); err != nil {
+		t.Fatalf("failed to add record: %s", err)
+	}
+
+	if _, err := client.RemoveTXT(ctx, rec); err != nil {
+		t.Fatalf("failed to remove record: %s", err)
+	}
+
+	// Only additions carry a lease
+	if got := ts.Leases(); !slices.Equal(got, []uint32{3600, 0}) {
+		t.Errorf("want leases [3600 0], got %v", got)
+	}
+}
+
+func TestRFC2136ClientWrongKey(t *testing.T) {
+	ts := newTestServer(t)
+	key := &tsigKey{
+		Name:      "acme-key.",
+		Algorithm: "hmac-sha256.",
+		Secret:    "d3Jvbmctc2VjcmV0",
+	}
+
+	client := newRFC2136Client(key)
+	client.nameservers = []string{ts.Addr}
+
+	rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}
+	_, err := client.AddTXT(context.Background(), rec)
+	if err == nil {
+		t.Fatal("want error when signing with the wrong key")
+	}
+
+	if ts.Updates() != 0 {
+		t.Fatalf("want no updates applied, got %d", ts.Updates())
+	}
+}
+
+func TestRFC2136ClientNameservers(t *testing.T) {
+	ts := newTestServer(t)
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	// The configured nameservers take precedence
+	t.Setenv("USE_NAMESERVER", "192.0.2.1")
+
+	client := newRFC2136Client(key)
+	client.nameservers = []string{ts.Addr}
+
+	rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}
+	result, err := client.AddTXT(context.Background(), rec)
+	if err != nil {
+		t.Fatalf("failed to add record: %s", err)
+	}
+
+	if result.Server != ts.Addr {
+		t.Errorf("want update sent to %s, got %s", ts.Addr, result.Server)
+	}
+}
+
+func TestFindNameserverUseNameserver(t *testing.T) {
+	testCases := []struct {
+		env     string
+		want    endpoint
+		wantErr error
+	}{
+		{env: "ns1.example.com", want: endpoint{Host: "ns1.example.com", Port: "53"}},
+		{env: "ns1.example.com:5353", want: endpoint{Host: "ns1.example.com", Port: "5353"}},
+		{env: "[2001:db8::1]:5353", want: endpoint{Host: "2001:db8::1", Port: "5353"}},
+		{env: "ns1.example.com:dns", wantErr: ErrInvalidNameserver},
+	}
+
+	for _, tc := range testCases {
+		t.Setenv("USE_NAMESERVER", tc.env)
+		got, err := findNameservers(context.Background(), &resolver{}, "example.com.", DefaultUpdatePort, DiscoverySOA, TransportAuto)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: want error %v, got %v", tc.env, tc.wantErr, err)
+			continue
+		}
+
+		if err == nil && !slices.Equal(got, []endpoint{tc.want}) {
+			t.Errorf("%s: want %+v, got %+v", tc.env, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrInvalidConcurrency is returned when a concurrency limit is not a
+// positive number.
+var ErrInvalidConcurrency = errors.New("concurrency limit must be positive")
+
+// DefaultMaxConcurrentUpdates is the default maximum number of
+// updates, which are in flight at the same time across all zones.
+const DefaultMaxConcurrentUpdates = 32
+
+// DefaultMaxConcurrentUpdatesPerZone is the default maximum number of
+// updates, which are in flight at the same time for a single zone.
+const DefaultMaxConcurrentUpdatesPerZone = 4
+
+// scheduler bounds the number of updates in flight, both globally and
+// per zone.  The updates to a zone are started in the order they were
+// scheduled, and an update of a name is not started before the
+// previous update of the same name has completed, so that adding and
+// deleting the same record cannot race.
+type scheduler struct {
+	mu sync.Mutex
+
+	// limit is the maximum number of updates in flight
+	limit int
+
+	// running is the number of updates in flight
+	running int
+
+	// zones contains the queues of the zones with pending or
+	// running updates
+	zones map[string]*zoneQueue
+}
+
+// zoneQueue contains the pending and running updates of a zone.
+type zoneQueue struct {
+	// limit is the maximum number of updates in flight for the
+	// zone
+	limit int
+
+	// running is the number of updates in flight for the zone
+	running int
+
+	// names contains the names with an update in flight
+	names map[string]bool
+
+	// pending are the updates waiting to be started, in order
+	pending []*task
+}
+
+// task is an update waiting to be started.
+type task struct {
+	// name is the name being updated
+	name string
+
+	// started is closed, once the update may start
+	started chan struct{}
+}
+
+// newScheduler creates a new scheduler, which allows up to limit
+// updates in flight.
+func newScheduler(limit int) *scheduler {
+	s := &scheduler{
+		limit: limit,
+		zones: make(map[string]*zoneQueue),
+	}
+
+	return s
+}
+
+// setLimit sets the maximum number of updates in flight.
+func (s *scheduler) setLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limit = limit
+	s.dispatch()
+}
+
+// acquire waits until an update of the name in the zone may start,
+// and returns a function, which must be called once the update has
+// completed.  The zoneLimit is the maximum number of updates in
+// flight for the zone.
+func (s *scheduler) acquire(ctx context.Context, zone, name string, zoneLimit int) (func(), error) {
+	return s.wait(ctx, zone, s.enqueue(zone, name, zoneLimit))
+}
+
+// enqueue adds an update of the name in the zone to the queue of the
+// zone, and returns its task, which must be waited for.  The
+// zoneLimit is the maximum number of updates in flight for the zone.
+func (s *scheduler) enqueue(zone, name string, zoneLimit int) *task {
+	t := &task{name: name, started: make(chan struct{})}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.zones[zone]
+	if !ok {
+		q = &zoneQueue{names: make(map[string]bool)}
+		s.zones[zone] = q
+	}
+	q.limit = zoneLimit
+	q.pending = append(q.pending, t)
+	s.dispatch()
+
+	return t
+}
+
+// wait waits until the enqueued update may start, and returns a
+// function, which must be called once the update has completed.
+func (s *scheduler) wait(ctx context.Context, zone string, t *task) (func(), error) {
+	release := func() { s.release(zone, t.name) }
+
+	select {
+	case <-t.started:
+		return release, nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The update may have been started in the meantime
+	select {
+	case <-t.started:
+		s.releaseLocked(zone, t.name)
+	default:
+		q := s.zones[zone]
+		q.pending = removeTask(q.pending, t)
+		s.cleanup(zone, q)
+		s.dispatch()
+	}
+
+	return nil, ctx.Err()
+}
+
+// release marks the update of the name in the zone as completed.
+func (s *scheduler) release(zone, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.releaseLocked(zone, name)
+}
+
+// releaseLocked marks the update of the name in the zone as completed
+// and starts the next updates.  It must be called with the lock held.
+func (s *scheduler) releaseLocked(zone, name string) {
+	q := s.zones[zone]
+	q.running--
+	delete(q.names, name)
+	s.running--
+	s.cleanup(zone, q)
+	s.dispatch()
+}
+
+// dispatch starts the pending updates, as long as the limits allow.
+// The updates of a zone are started in order, i.e. an update, which
+// must wait for the previous update of the same name, holds back the
+// subsequent updates of the zone.  It must be called with the lock
+// held.
+func (s *scheduler) dispatch() {
+	for _, q := range s.zones {
+		for len(q.pending) > 0 && s.running < s.limit && q.running < q.limit {
+			t := q.pending[0]
+			if q.names[t.name] {
+				break
+			}
+
+			q.pending = q.pending[1:]
+			q.names[t.name] = true
+			q.running++
+			s.running++
+			close(t.started)
+		}
+	}
+}
+
+// cleanup removes the queue of the zone, once it has no pending or
+// running updates.  It must be called with the lock held.
+func (s *scheduler) cleanup(zone string, q *zoneQueue) {
+	if q.running == 0 && len(q.pending) == 0 {
+		delete(s.zones, zone)
+	}
+}
+
+// removeTask removes the task from the list.
+func removeTask(tasks []*task, t *task) []*task {
+	for i := range tasks {
+		if tasks[i] == t {
+			return append(tasks[:i:i], tasks[i+1:]...)
+		}
+	}
+
+	return tasks
+}
+
+// scheduledUpdater is an Updater, which schedules the updates of
+// another Updater.
+type scheduledUpdater struct {
+	scheduler *scheduler
+	updater   Updater
+	zoneLimit int
+}
+
+// AddTXT implements the Updater interface
+func (u *scheduledUpdater) AddTXT(ctx context.Context, rec ChallengeRecord) (Result, error) {
+	release, err := u.scheduler.acquire(ctx, rec.Zone, rec.FQDN, u.zoneLimit)
+	if err != nil {
+		return Result{}, err
+	}
+	defer release()
+
+	return u.updater.AddTXT(ctx, rec)
+}
+
+// RemoveTXT implements the Updater interface
+func (u *scheduledUpdater) RemoveTXT(ctx context.Context, rec ChallengeRecord) (Result, error) {
+	release, err := u.scheduler.acquire(ctx, rec.Zone, rec.FQDN, u.zoneLimit)
+	if err != nil {
+		return Result{}, err
+	}
+	defer release()
+
+	return u.updater.RemoveTXT(ctx, rec)
+}
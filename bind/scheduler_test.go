@@ -0,0 +1,186 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingUpdater is an Updater, which records the order of the
+// updates and the maximum number of updates in flight.
+type recordingUpdater struct {
+	mu       sync.Mutex
+	running  int
+	maxSeen  int
+	calls    []string
+	duration time.Duration
+}
+
+func (u *recordingUpdater) do(op string, rec ChallengeRecord) (Result, error) {
+	u.mu.Lock()
+	u.running++
+	u.maxSeen = max(u.maxSeen, u.running)
+	u.calls = append(u.calls, fmt.Sprintf("%s %s %s", op, rec.FQDN, rec.Value))
+	u.mu.Unlock()
+
+	time.Sleep(u.duration)
+
+	u.mu.Lock()
+	u.running--
+	u.mu.Unlock()
+
+	return Result{Changed: true}, nil
+}
+
+func (u *recordingUpdater) AddTXT(ctx context.Context, rec ChallengeRecord) (Result, error) {
+	return u.do("add", rec)
+}
+
+func (u *recordingUpdater) RemoveTXT(ctx context.Context, rec ChallengeRecord) (Result, error) {
+	return u.do("remove", rec)
+}
+
+func TestSchedulerLimits(t *testing.T) {
+	testCases := []struct {
+		limit     int
+		zoneLimit int
+		zones     int
+		want      int
+	}{
+		{limit: 10, zoneLimit: 2, zones: 1, want: 2},
+		{limit: 10, zoneLimit: 2, zones: 3, want: 6},
+		{limit: 3, zoneLimit: 2, zones: 3, want: 3},
+	}
+
+	for _, tc := range testCases {
+		updater := &recordingUpdater{duration: 20 * time.Millisecond}
+		u := &scheduledUpdater{scheduler: newScheduler(tc.limit), updater: updater, zoneLimit: tc.zoneLimit}
+
+		var wg sync.WaitGroup
+		for z := 0; z < tc.zones; z++ {
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(z, i int) {
+					defer wg.Done()
+					zone := fmt.Sprintf("example-%d.com.", z)
+					rec := ChallengeRecord{Zone: zone, FQDN: fmt.Sprintf("_acme-challenge.%d.%s", i, zone), Value: "token"}
+					if _, err := u.AddTXT(context.Background(), rec); err != nil {
+						t.Errorf("failed to add record: %s", err)
+					}
+				}(z, i)
+			}
+		}
+		wg.Wait()
+
+		if updater.maxSeen != tc.want {
+			t.Errorf("limit %d, zone limit %d, %d zones: want %d updates in flight, got %d", tc.limit, tc.zoneLimit, tc.zones, tc.want, updater.maxSeen)
+		}
+
+		if len(updater.calls) != tc.zones*10 {
+			t.Errorf("want %d updates, got %d", tc.zones*10, len(updater.calls))
+		}
+	}
+}
+
+func TestSchedulerOrder(t *testing.T) {
+	updater := &recordingUpdater{duration: 5 * time.Millisecond}
+	s := newScheduler(10)
+	u := &scheduledUpdater{scheduler: s, updater: updater, zoneLimit: 4}
+
+	rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", Value: "token"}
+
+	// Hold the name, so that the following updates queue up in order
+	release, err := s.acquire(context.Background(), rec.Zone, rec.FQDN, 4)
+	if err != nil {
+		t.Fatalf("failed to acquire: %s", err)
+	}
+
+	var want []string
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		rec.Value = fmt.Sprintf("token-%d", i)
+		want = append(want, "add "+rec.FQDN+" "+rec.Value, "remove "+rec.FQDN+" "+rec.Value)
+
+		wg.Add(2)
+		go func(rec ChallengeRecord) {
+			defer wg.Done()
+			u.AddTXT(context.Background(), rec)
+		}(rec)
+		waitPending(t, s, rec.Zone, 2*i+1)
+
+		go func(rec ChallengeRecord) {
+			defer wg.Done()
+			u.RemoveTXT(context.Background(), rec)
+		}(rec)
+		waitPending(t, s, rec.Zone, 2*i+2)
+	}
+
+	release()
+	wg.Wait()
+
+	if !slices.Equal(updater.calls, want) {
+		t.Errorf("want %v, got %v", want, updater.calls)
+	}
+
+	if updater.maxSeen != 1 {
+		t.Errorf("want updates of the same name serialized, got %d in flight", updater.maxSeen)
+	}
+
+	if len(s.zones) != 0 {
+		t.Errorf("want no zone queues left, got %d", len(s.zones))
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	s := newScheduler(1)
+	release, err := s.acquire(context.Background(), "example.com.", "a.example.com.", 1)
+	if err != nil {
+		t.Fatalf("failed to acquire: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.acquire(ctx, "example.org.", "b.example.org.", 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, got %v", err)
+	}
+
+	release()
+
+	// The cancelled update must not hold a slot
+	release, err = s.acquire(context.Background(), "example.org.", "b.example.org.", 1)
+	if err != nil {
+		t.Fatalf("failed to acquire: %s", err)
+	}
+	release()
+
+	if s.running != 0 || len(s.zones) != 0 {
+		t.Errorf("want no updates left, got %d running in %d zones", s.running, len(s.zones))
+	}
+}
+
+// waitPending waits until the zone has n pending updates.
+func waitPending(t *testing.T, s *scheduler, zone string, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		q := s.zones[zone]
+		pending := 0
+		if q != nil {
+			pending = len(q.pending)
+		}
+		s.mu.Unlock()
+
+		if pending == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("want %d pending updates for %s", n, zone)
+}
@@ -0,0 +1,255 @@
+package bind
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// ErrHookFailed is returned when the hook reports, that it failed
+// to handle the request.
+var ErrHookFailed = errors.New("hook failed")
+
+// ErrScriptRequiresTSIGKey is returned when the script backend is
+// used without a TSIG key, e.g. with a SIG(0) key.
+var ErrScriptRequiresTSIGKey = errors.New("script backend requires a TSIG key")
+
+// HookProtocolVersion is the version of the hook protocol, which is
+// used to communicate with the ACME helper script.
+const HookProtocolVersion = 1
+
+// DefaultHookTimeout is the time after which the hook is killed,
+// unless specified in the configuration.
+const DefaultHookTimeout = 30 * time.Second
+
+// Operations passed to the hook.
+const (
+	// HookOperationPresent requests the TXT record to be created
+	HookOperationPresent = "present"
+
+	// HookOperationCleanUp requests the TXT record to be deleted
+	HookOperationCleanUp = "cleanup"
+)
+
+// HookRequest is passed as JSON to the hook on standard input.
+type HookRequest struct {
+	// Version is the version of the hook protocol
+	Version int `json:"version"`
+
+	// Operation is either "present" or "cleanup"
+	Operation string `json:"operation"`
+
+	// UID is the unique identifier of the challenge
+	UID string `json:"uid"`
+
+	// DNSName is the name of the domain, which is being
+	// validated
+	DNSName string `json:"dnsName"`
+
+	// ResourceNamespace is the namespace of the issuer
+	ResourceNamespace string `json:"resourceNamespace"`
+
+	// Zone is the zone in which the record is managed
+	Zone string `json:"zone"`
+
+	// FQDN is the fully qualified name of the TXT record
+	FQDN string `json:"fqdn"`
+
+	// TTL is the time-to-live of the TXT record
+	TTL int `json:"ttl"`
+
+	// Key is the value of the TXT record
+	Key string `json:"key"`
+
+	// Nameservers are the nameservers to send the update to, in
+	// order, i.e. host, host:port or [v6]:port.  If empty, the
+	// hook discovers the nameservers of the zone on its own.
+	Nameservers []string `json:"nameservers,omitempty"`
+
+	// Resolvers are the recursive resolvers, which the hook uses
+	// to discover the nameservers on its own, if any
+	Resolvers []string `json:"resolvers,omitempty"`
+
+	// TSIGKeyFile is the path to the TSIG key, which is only
+	// valid for the duration of the call
+	TSIGKeyFile string `json:"tsigKeyFile"`
+}
+
+// HookResult is written as JSON by the hook on standard output.
+type HookResult struct {
+	// Version is the version of the hook protocol
+	Version int `json:"version"`
+
+	// Success specifies whether the hook handled the request
+	// successfully
+	Success bool `json:"success"`
+
+	// Message is an optional human-readable message
+	Message string `json:"message,omitempty"`
+}
+
+// scriptUpdater is an Updater, which calls the ACME helper script
+// to create and delete the TXT records.
+type scriptUpdater struct {
+	// script is the path to the ACME helper script
+	script string
+
+	// cfg is the solver configuration
+	cfg *BindProviderConfig
+
+	// resolver is used to discover the nameservers.  If nil, the
+	// resolver of the system is used.
+	resolver *resolver
+}
+
+// AddTXT implements the Updater interface.  The hook does not report
+// whether the record already existed, so a change is always assumed.
+func (s *scriptUpdater) AddTXT(ctx context.Context, rec ChallengeRecord) (Result, error) {
+	if err := s.run(ctx, HookOperationPresent, rec); err != nil {
+		return Result{}, err
+	}
+
+	return Result{Changed: true}, nil
+}
+
+// RemoveTXT implements the Updater interface.  The hook does not
+// report whether the record existed, so a change is always assumed.
+func (s *scriptUpdater) RemoveTXT(ctx context.Context, rec ChallengeRecord) (Result, error) {
+	if err := s.run(ctx, HookOperationCleanUp, rec); err != nil {
+		return Result{}, err
+	}
+
+	return Result{Changed: true}, nil
+}
+
+// run calls the helper script with the given operation.  The
+// request is passed as JSON on standard input and the result is
+// read as JSON from standard output.
+func (s *scriptUpdater) run(ctx context.Context, op string, rec ChallengeRecord) error {
+	if len(s.cfg.tsigKey) == 0 {
+		return ErrScriptRequiresTSIGKey
+	}
+
+	// Dump the TSIG key locally, so that we can pass it to
+	// the helper scripts. Make sure to delete it afterwards.
+	tsigFile, err := s.cfg.dumpTSIGKey("")
+	if err != nil {
+		return fmt.Errorf("failed to dump TSIG key: %s", err)
+	}
+	defer os.Remove(tsigFile.Name())
+	tsigFile.Close()
+
+	req := HookRequest{
+		Version:           HookProtocolVersion,
+		Operation:         op,
+		UID:               rec.UID,
+		DNSName:           rec.DNSName,
+		ResourceNamespace: rec.ResourceNamespace,
+		Zone:              rec.Zone,
+		FQDN:              rec.FQDN,
+		TTL:               rec.TTL,
+		Key:               rec.Value,
+		Nameservers:       s.nameservers(ctx, rec.Zone),
+		Resolvers:         s.cfg.Resolvers,
+		TSIGKeyFile:       tsigFile.Name(),
+	}
+
+	stdin, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.HookTimeout.Duration)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.script)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+	killProcessGroup(cmd)
+
+	runErr := cmd.Run()
+	hookStderr := strings.TrimSpace(stderr.String())
+	if hookStderr != "" {
+		klog.InfoS("hook stderr", "hook", s.script, "operation", op, "fqdn", rec.FQDN, "stderr", hookStderr)
+	}
+
+	if runErr != nil && ctx.Err() == context.DeadlineExceeded {
+		return hookError(fmt.Errorf("hook timed out after %s", s.cfg.HookTimeout.Duration), hookStderr)
+	}
+
+	// Hooks may exit with a non-zero status after writing a failure
+	// result, which is preferred over the bare exit status.
+	var result HookResult
+	parseErr := json.Unmarshal(stdout.Bytes(), &result)
+	if runErr != nil && (parseErr != nil || result.Version != HookProtocolVersion || result.Success) {
+		return hookError(runErr, hookStderr)
+	}
+
+	if parseErr != nil {
+		return hookError(fmt.Errorf("invalid hook result: %w", parseErr), hookStderr)
+	}
+
+	if result.Version != HookProtocolVersion {
+		return hookError(fmt.Errorf("unsupported hook protocol version %d", result.Version), hookStderr)
+	}
+
+	if !result.Success {
+		return hookError(fmt.Errorf("%w: %s", ErrHookFailed, result.Message), hookStderr)
+	}
+
+	return nil
+}
+
+// nameservers returns the nameservers passed to the hook, i.e. the
+// configured nameservers, if any, or else the discovered nameservers
+// of the zone, in the order they should be tried.  If discovery
+// fails, the hook is left to find the nameservers on its own.
+func (s *scriptUpdater) nameservers(ctx context.Context, zone string) []string {
+	if len(s.cfg.Nameservers) > 0 {
+		return s.cfg.Nameservers
+	}
+
+	r := s.resolver
+	if r == nil {
+		var err error
+		if r, err = systemResolver(); err != nil {
+			klog.InfoS("failed to discover nameservers", "zone", zone, "err", err)
+			return nil
+		}
+	}
+
+	// nsupdate sends the updates over UDP and falls back to TCP
+	endpoints, err := findNameservers(ctx, r, zone, DefaultUpdatePort, s.cfg.Discovery, TransportAuto)
+	if err != nil {
+		klog.InfoS("failed to discover nameservers", "zone", zone, "err", err)
+		return nil
+	}
+
+	nameservers := make([]string, 0, len(endpoints))
+	for _, ep := range endpoints {
+		nameservers = append(nameservers, ep.String())
+	}
+
+	return nameservers
+}
+
+// hookError annotates the error with the standard error output of
+// the hook, if any.
+func hookError(err error, stderr string) error {
+	if stderr == "" {
+		return err
+	}
+
+	return fmt.Errorf("%w (stderr: %s)", err, stderr)
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package bind
+
+import (
+	"os/exec"
+)
+
+// killProcessGroup is a no-op on platforms without process groups,
+// where only the hook process itself is killed on cancellation.
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,164 @@
+//go:build unix
+
+package bind
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newTestHook creates an executable hook with the given body in a
+// temporary directory.
+func newTestHook(t *testing.T, body string) *scriptUpdater {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "hook.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatalf("failed to create hook: %s", err)
+	}
+
+	cfg := &BindProviderConfig{
+		HookTimeout: metav1.Duration{Duration: DefaultHookTimeout},
+		tsigKey:     []byte(testKey),
+	}
+
+	// Do not discover the nameservers
+	return &scriptUpdater{script: path, cfg: cfg, resolver: &resolver{}}
+}
+
+func TestScriptUpdaterRequest(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "request.json")
+	hook := newTestHook(t, `cat > `+out+`
+echo '{"version": 1, "success": true}'
+`)
+
+	hook.cfg.Nameservers = []string{"ns1.example.com:5353"}
+	hook.cfg.Resolvers = []string{"192.0.2.53"}
+
+	rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token; rm -rf /"}
+	if _, err := hook.AddTXT(context.Background(), rec); err != nil {
+		t.Fatalf("hook failed: %s", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read request: %s", err)
+	}
+
+	for _, want := range []string{`"version":1`, `"operation":"present"`, `"key":"token; rm -rf /"`, `"tsigKeyFile":`, `"nameservers":["ns1.example.com:5353"]`, `"resolvers":["192.0.2.53"]`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("want %s in request, got %s", want, data)
+		}
+	}
+}
+
+func TestScriptUpdaterDiscovery(t *testing.T) {
+	ts := newTestServer(t)
+	ts.AddRecords(t,
+		"example.com. 3600 IN SOA ns0.example.com. hostmaster.example.com. 1 7200 3600 1209600 300",
+		"example.com. 3600 IN NS ns1.example.com.",
+	)
+
+	out := filepath.Join(t.TempDir(), "request.json")
+	hook := newTestHook(t, `cat > `+out+`
+echo '{"version": 1, "success": true}'
+`)
+	hook.resolver = &resolver{Servers: []string{ts.Addr}}
+
+	rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token-1"}
+	if _, err := hook.AddTXT(context.Background(), rec); err != nil {
+		t.Fatalf("hook failed: %s", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read request: %s", err)
+	}
+
+	if want := `"nameservers":["ns0.example.com:53","ns1.example.com:53"]`; !strings.Contains(string(data), want) {
+		t.Errorf("want %s in request, got %s", want, data)
+	}
+}
+
+func TestScriptUpdaterFailure(t *testing.T) {
+	hook := newTestHook(t, `echo "update refused" >&2
+echo '{"version": 1, "success": false, "message": "nsupdate failed"}'
+`)
+
+	rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token"}
+	_, err := hook.RemoveTXT(context.Background(), rec)
+	if !errors.Is(err, ErrHookFailed) {
+		t.Fatalf("want ErrHookFailed, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "update refused") {
+		t.Fatalf("want stderr in error, got %s", err)
+	}
+}
+
+func TestScriptUpdaterFailureExitStatus(t *testing.T) {
+	testCases := []struct {
+		body    string
+		wantErr error
+		want    string
+	}{
+		{
+			body: `echo '{"version": 1, "success": false, "message": "nsupdate failed"}'
+exit 1
+`,
+			wantErr: ErrHookFailed,
+			want:    "nsupdate failed",
+		},
+		{
+			body: `echo "no result" >&2
+exit 2
+`,
+			want: "exit status 2",
+		},
+		{
+			body: `echo '{"version": 1, "success": true}'
+exit 1
+`,
+			want: "exit status 1",
+		},
+	}
+
+	for _, tc := range testCases {
+		hook := newTestHook(t, tc.body)
+		rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token"}
+		_, err := hook.AddTXT(context.Background(), rec)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: want %q in error, got %v", tc.body, tc.want, err)
+			continue
+		}
+
+		if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: want %v, got %v", tc.body, tc.wantErr, err)
+		}
+	}
+}
+
+func TestScriptUpdaterTimeout(t *testing.T) {
+	hook := newTestHook(t, `sleep 60 &
+wait
+`)
+	hook.cfg.HookTimeout.Duration = 100 * time.Millisecond
+
+	start := time.Now()
+	rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token"}
+	_, err := hook.AddTXT(context.Background(), rec)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("want timeout error, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("hook was not killed in time, took %s", elapsed)
+	}
+}
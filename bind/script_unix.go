@@ -0,0 +1,18 @@
+//go:build unix
+
+package bind
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroup starts the command in a new process group and
+// kills the whole group when the command is cancelled, so that no
+// child processes of the hook are left behind.
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,538 @@
+package bind
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// testKey is the TSIG key used by the test nameserver.
+const testKey = `key "acme-key" {
+	algorithm hmac-sha256;
+	secret "e6wQB/TVQ8ka38vh6CyGjUTnLH4EkUJhsLaiO0JgbPU=";
+};
+`
+
+// testServer is a minimal authoritative nameserver, which accepts
+// TSIG-signed dynamic updates and keeps the TXT records in memory.
+// The server listens on the same port for both UDP and TCP.
+type testServer struct {
+	// Addr is the address the server listens on
+	Addr string
+
+	mu          sync.Mutex
+	records     map[string][]string
+	updates     int
+	requests    map[string]int
+	remoteAddrs []string
+	truncateUDP bool
+	sig0Key     *dns.KEY
+	rcode       int
+	signing     string
+	clockSkew   time.Duration
+	failUpdates int
+	failRcode   int
+	dropUpdates int
+	leases      []uint32
+	notifies    []string
+	rrs         []dns.RR
+	servers     []*dns.Server
+}
+
+// newTestServer starts a new test nameserver on the loopback
+// interface, which accepts updates signed with the testKey.
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	return newTestServerAddr(t, "127.0.0.1:0")
+}
+
+// newTestServerAddr starts a new test nameserver on the given
+// address.  If the port is zero, a port is chosen, which is available
+// for both UDP and TCP.
+func newTestServerAddr(t *testing.T, addr string) *testServer {
+	t.Helper()
+
+	var (
+		pc  net.PacketConn
+		l   net.Listener
+		err error
+	)
+
+	// Find a port, which is available for both UDP and TCP
+	for i := 0; i < 10; i++ {
+		pc, err = net.ListenPacket("udp", addr)
+		if err != nil {
+			t.Fatalf("failed to listen: %s", err)
+		}
+
+		l, err = net.Listen("tcp", pc.LocalAddr().String())
+		if err == nil {
+			break
+		}
+		pc.Close()
+	}
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+
+	ts := &testServer{
+		Addr:     pc.LocalAddr().String(),
+		records:  make(map[string][]string),
+		requests: make(map[string]int),
+	}
+
+	ts.start(t, &dns.Server{PacketConn: pc})
+	ts.start(t, &dns.Server{Listener: l})
+
+	return ts
+}
+
+// newTestTLSServer starts a new test nameserver on the loopback
+// interface, which accepts updates over TLS only.  Clients must
+// present a certificate issued by the returned CA, whose certificate
+// and key can be used as the client certificate as well.
+func newTestTLSServer(t *testing.T) (ts *testServer, certPEM, keyPEM []byte) {
+	t.Helper()
+
+	certPEM, keyPEM = newTestCertificate(t)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("failed to load test certificate: %s", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(certPEM)
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+
+	ts = &testServer{
+		Addr:     l.Addr().String(),
+		records:  make(map[string][]string),
+		requests: make(map[string]int),
+	}
+	ts.start(t, &dns.Server{Listener: l, Net: "tcp-tls"})
+
+	return ts, certPEM, keyPEM
+}
+
+// newTestCertificate creates a self-signed certificate for
+// ns1.example.com and 127.0.0.1, which can be used both as a server
+// and a client certificate.
+func newTestCertificate(t *testing.T) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ns1.example.com"},
+		DNSNames:              []string{"ns1.example.com"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %s", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM
+}
+
+// start starts the given server, which handles the requests of the
+// test nameserver.
+func (ts *testServer) start(t *testing.T, server *dns.Server) {
+	t.Helper()
+
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse test key: %s", err)
+	}
+
+	started := make(chan struct{})
+	server.TsigSecret = map[string]string{key.Name: key.Secret}
+	server.Handler = dns.HandlerFunc(ts.handle)
+	server.MsgAcceptFunc = func(dns.Header) dns.MsgAcceptAction { return dns.MsgAccept }
+	server.NotifyStartedFunc = func() { close(started) }
+
+	go server.ActivateAndServe()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("test nameserver did not start")
+	}
+	t.Cleanup(func() { server.Shutdown() })
+	ts.servers = append(ts.servers, server)
+}
+
+// TruncateUDP makes the server respond with truncated responses
+// over UDP.
+func (ts *testServer) TruncateUDP() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.truncateUDP = true
+}
+
+// AcceptSIG0 makes the server accept updates signed with the SIG(0)
+// key.
+func (ts *testServer) AcceptSIG0(key *dns.KEY) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.sig0Key = key
+}
+
+// Respond makes the server respond to updates with the given
+// response code, without applying them.
+func (ts *testServer) Respond(rcode int) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.rcode = rcode
+}
+
+// AddRecords adds the records, which the server answers queries
+// with, e.g. the SOA and NS records of a zone.
+func (ts *testServer) AddRecords(t *testing.T, records ...string) {
+	t.Helper()
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			t.Fatalf("failed to parse record %q: %s", record, err)
+		}
+		ts.rrs = append(ts.rrs, rr)
+	}
+}
+
+// FailUpdates makes the server respond to the next n updates with
+// the given response code, without applying them.
+func (ts *testServer) FailUpdates(n, rcode int) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.failUpdates = n
+	ts.failRcode = rcode
+}
+
+// DropUpdates makes the server ignore the next n updates, without
+// responding to them.
+func (ts *testServer) DropUpdates(n int) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.dropUpdates = n
+}
+
+// SkewClock makes the server check the time of TSIG signatures
+// against a clock, which is off by the given duration.
+func (ts *testServer) SkewClock(d time.Duration) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.clockSkew = d
+}
+
+// SignResponses controls how the server signs its responses to
+// TSIG-signed requests, i.e. "unsigned" or "invalid".  By default
+// responses are signed with the TSIG key of the request.
+func (ts *testServer) SignResponses(signing string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.signing = signing
+}
+
+// Requests returns the number of requests received over the given
+// network, i.e. udp or tcp.
+func (ts *testServer) Requests(network string) int {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.requests[network]
+}
+
+// RemoteAddrs returns the addresses the requests were received from.
+func (ts *testServer) RemoteAddrs() []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return slices.Clone(ts.remoteAddrs)
+}
+
+// Leases returns the EDNS0 update leases of the accepted UPDATE
+// messages in seconds, which is zero for updates without a lease.
+func (ts *testServer) Leases() []uint32 {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return slices.Clone(ts.leases)
+}
+
+// Notifies returns the zones of the received NOTIFY messages.
+func (ts *testServer) Notifies() []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return slices.Clone(ts.notifies)
+}
+
+// TXT returns the TXT records for the given name.
+func (ts *testServer) TXT(name string) []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return slices.Clone(ts.records[dns.CanonicalName(name)])
+}
+
+// Updates returns the number of accepted UPDATE messages.
+func (ts *testServer) Updates() int {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.updates
+}
+
+// handle handles a single request.
+func (ts *testServer) handle(w dns.ResponseWriter, req *dns.Msg) {
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+
+	network := w.RemoteAddr().Network()
+	ts.mu.Lock()
+	ts.requests[network]++
+	ts.remoteAddrs = append(ts.remoteAddrs, w.RemoteAddr().String())
+	truncate := ts.truncateUDP && network == "udp"
+	rcode := ts.rcode
+	signing := ts.signing
+	now := time.Now().Add(ts.clockSkew)
+	drop := false
+	if req.Opcode == dns.OpcodeUpdate {
+		switch {
+		case ts.dropUpdates > 0:
+			ts.dropUpdates--
+			drop = true
+		case ts.failUpdates > 0:
+			ts.failUpdates--
+			rcode = ts.failRcode
+		}
+	}
+	ts.mu.Unlock()
+
+	if drop {
+		return
+	}
+
+	if tsig := req.IsTsig(); tsig != nil {
+		status := w.TsigStatus()
+		if status == nil && now.Sub(time.Unix(int64(tsig.TimeSigned), 0)).Abs() > time.Duration(tsig.Fudge)*time.Second {
+			status = dns.ErrTime
+		}
+		if status != nil {
+			ts.writeTSIGError(w, resp, tsig, status, now)
+			return
+		}
+	}
+
+	if truncate {
+		resp.Truncated = true
+		if tsig := req.IsTsig(); tsig != nil && w.TsigStatus() == nil {
+			resp.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsig.Fudge, time.Now().Unix())
+		}
+		w.WriteMsg(resp)
+		return
+	}
+
+	switch req.Opcode {
+	case dns.OpcodeUpdate:
+		if !ts.authorized(w, req) {
+			resp.Rcode = dns.RcodeRefused
+			break
+		}
+		if rcode != dns.RcodeSuccess {
+			resp.Rcode = rcode
+			break
+		}
+		ts.applyUpdate(req)
+	case dns.OpcodeQuery:
+		resp.Authoritative = true
+		q := req.Question[0]
+		for _, rr := range ts.TXT(q.Name) {
+			if q.Qtype == dns.TypeTXT {
+				resp.Answer = append(resp.Answer, newTXT(q.Name, 300, rr))
+			}
+		}
+		ts.mu.Lock()
+		for _, rr := range ts.rrs {
+			if rr.Header().Rrtype == q.Qtype && dns.CanonicalName(rr.Header().Name) == dns.CanonicalName(q.Name) {
+				resp.Answer = append(resp.Answer, rr)
+			}
+		}
+		ts.mu.Unlock()
+	case dns.OpcodeNotify:
+		resp.Authoritative = true
+		ts.mu.Lock()
+		ts.notifies = append(ts.notifies, req.Question[0].Name)
+		ts.mu.Unlock()
+	default:
+		resp.Rcode = dns.RcodeNotImplemented
+	}
+
+	tsig := req.IsTsig()
+	switch {
+	case tsig == nil || signing == "unsigned":
+		w.WriteMsg(resp)
+	case signing == "invalid":
+		// Sign the response with a different secret
+		resp.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsig.Fudge, time.Now().Unix())
+		buf, _, err := dns.TsigGenerate(resp, "aW52YWxpZA==", tsig.MAC, false)
+		if err == nil {
+			w.Write(buf)
+		}
+	default:
+		resp.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsig.Fudge, time.Now().Unix())
+		w.WriteMsg(resp)
+	}
+}
+
+// writeTSIGError responds to a request, whose TSIG signature could
+// not be verified, with NOTAUTH and the TSIG error in an unsigned TSIG
+// record, as described in RFC 8945, section 5.2.  BADTIME responses
+// include the time of the server in the Other Data field.
+func (ts *testServer) writeTSIGError(w dns.ResponseWriter, resp *dns.Msg, tsig *dns.TSIG, status error, now time.Time) {
+	tsigError := uint16(dns.RcodeBadSig)
+	switch status {
+	case dns.ErrSecret, dns.ErrKeyAlg:
+		tsigError = dns.RcodeBadKey
+	case dns.ErrTime:
+		tsigError = dns.RcodeBadTime
+	}
+
+	respTsig := &dns.TSIG{
+		Hdr:        dns.RR_Header{Name: tsig.Hdr.Name, Rrtype: dns.TypeTSIG, Class: dns.ClassANY},
+		Algorithm:  tsig.Algorithm,
+		TimeSigned: tsig.TimeSigned,
+		Fudge:      tsig.Fudge,
+		OrigId:     resp.Id,
+		Error:      tsigError,
+	}
+	if tsigError == dns.RcodeBadTime {
+		respTsig.OtherLen = 6
+		respTsig.OtherData = fmt.Sprintf("%012x", now.Unix())
+	}
+
+	resp.Rcode = dns.RcodeNotAuth
+	resp.Extra = append(resp.Extra, respTsig)
+
+	buf, err := resp.Pack()
+	if err != nil {
+		return
+	}
+	w.Write(buf)
+}
+
+// authorized returns true, if the request is signed with either the
+// TSIG key or the SIG(0) key of the server.
+func (ts *testServer) authorized(w dns.ResponseWriter, req *dns.Msg) bool {
+	if req.IsTsig() != nil {
+		return w.TsigStatus() == nil
+	}
+
+	ts.mu.Lock()
+	key := ts.sig0Key
+	ts.mu.Unlock()
+
+	if key == nil || len(req.Extra) == 0 {
+		return false
+	}
+
+	sig, ok := req.Extra[len(req.Extra)-1].(*dns.SIG)
+	if !ok {
+		return false
+	}
+
+	buf, err := req.Pack()
+	if err != nil {
+		return false
+	}
+
+	return sig.Verify(key, buf) == nil
+}
+
+// applyUpdate applies the records from the update section of an
+// UPDATE message, and records its lease.
+func (ts *testServer) applyUpdate(req *dns.Msg) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	var lease uint32
+	if opt := req.IsEdns0(); opt != nil {
+		for _, o := range opt.Option {
+			if ul, ok := o.(*dns.EDNS0_UL); ok {
+				lease = ul.Lease
+			}
+		}
+	}
+
+	ts.updates++
+	ts.leases = append(ts.leases, lease)
+	for _, rr := range req.Ns {
+		txt, ok := rr.(*dns.TXT)
+		if !ok {
+			continue
+		}
+
+		name := dns.CanonicalName(txt.Hdr.Name)
+		value := txt.Txt[0]
+		switch txt.Hdr.Class {
+		case dns.ClassINET:
+			if !slices.Contains(ts.records[name], value) {
+				ts.records[name] = append(ts.records[name], value)
+			}
+		case dns.ClassNONE:
+			ts.records[name] = slices.DeleteFunc(ts.records[name], func(v string) bool { return v == value })
+		}
+	}
+}
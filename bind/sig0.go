@@ -0,0 +1,103 @@
+package bind
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+)
+
+// ErrInvalidSIG0Key is returned when the SIG(0) key could not be
+// parsed.
+var ErrInvalidSIG0Key = errors.New("invalid SIG(0) key")
+
+// Default keys of the entries in the secret, which hold the SIG(0)
+// key pair.
+const (
+	// DefaultSIG0PublicKey is the default key of the entry
+	// holding the KEY record
+	DefaultSIG0PublicKey = "sig0.key"
+
+	// DefaultSIG0PrivateKey is the default key of the entry
+	// holding the private key
+	DefaultSIG0PrivateKey = "sig0.private"
+)
+
+// sig0Validity is the time window around the current time, within
+// which SIG(0) signatures are valid.
+const sig0Validity = 5 * time.Minute
+
+// SIG0KeySelector references a secret, which holds a SIG(0) key
+// pair as generated by dnssec-keygen(8), i.e. the contents of the
+// K<name>+<alg>+<id>.key and K<name>+<alg>+<id>.private files.
+type SIG0KeySelector struct {
+	// The name of the secret in the issuer's namespace
+	cmmeta.LocalObjectReference `json:",inline"`
+
+	// PublicKey is the key of the entry holding the KEY record.
+	// Defaults to sig0.key
+	PublicKey string `json:"publicKey,omitempty"`
+
+	// PrivateKey is the key of the entry holding the private
+	// key. Defaults to sig0.private
+	PrivateKey string `json:"privateKey,omitempty"`
+}
+
+// sig0Key represents a SIG(0) key pair.
+type sig0Key struct {
+	// Key is the public KEY record
+	Key *dns.KEY
+
+	// Signer is the private key
+	Signer crypto.Signer
+}
+
+// parseSIG0Key parses the SIG(0) key pair from the public KEY
+// record and the private key in the format of dnssec-keygen(8).
+func parseSIG0Key(public, private []byte) (*sig0Key, error) {
+	rr, err := dns.NewRR(string(public))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSIG0Key, err)
+	}
+
+	key, ok := rr.(*dns.KEY)
+	if !ok {
+		return nil, fmt.Errorf("%w: not a KEY record", ErrInvalidSIG0Key)
+	}
+
+	privKey, err := key.ReadPrivateKey(bytes.NewReader(private), DefaultSIG0PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSIG0Key, err)
+	}
+
+	signer, ok := privKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported private key", ErrInvalidSIG0Key)
+	}
+
+	k := &sig0Key{
+		Key:    key,
+		Signer: signer,
+	}
+
+	return k, nil
+}
+
+// sign signs the message and returns it in wire format, with the
+// SIG(0) record appended.
+func (k *sig0Key) sign(msg *dns.Msg) ([]byte, error) {
+	now := time.Now()
+	sig := new(dns.SIG)
+	sig.Algorithm = k.Key.Algorithm
+	sig.KeyTag = k.Key.KeyTag()
+	sig.SignerName = k.Key.Hdr.Name
+	sig.Inception = uint32(now.Add(-sig0Validity).Unix())
+	sig.Expiration = uint32(now.Add(sig0Validity).Unix())
+
+	return sig.Sign(k.Signer, msg)
+}
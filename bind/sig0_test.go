@@ -0,0 +1,102 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// newTestSIG0Key generates a new SIG(0) key pair and returns it in
+// the format of dnssec-keygen(8).
+func newTestSIG0Key(t *testing.T) (public, private []byte) {
+	t.Helper()
+
+	key := &dns.KEY{
+		DNSKEY: dns.DNSKEY{
+			Hdr:       dns.RR_Header{Name: "acme-sig0.example.com.", Rrtype: dns.TypeKEY, Class: dns.ClassINET},
+			Flags:     512,
+			Protocol:  3,
+			Algorithm: dns.ECDSAP256SHA256,
+		},
+	}
+
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatalf("failed to generate SIG(0) key: %s", err)
+	}
+
+	return []byte(key.String()), []byte(key.PrivateKeyString(priv))
+}
+
+func TestSIG0Update(t *testing.T) {
+	public, private := newTestSIG0Key(t)
+	key, err := parseSIG0Key(public, private)
+	if err != nil {
+		t.Fatalf("failed to parse SIG(0) key: %s", err)
+	}
+
+	ts := newTestServer(t)
+	ts.AcceptSIG0(key.Key)
+
+	client := newSIG0RFC2136Client(key)
+	client.nameservers = []string{ts.Addr}
+
+	for _, net := range []string{TransportUDP, TransportTCP} {
+		client.transport.Net = net
+		rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: net}
+		if _, err := client.AddTXT(context.Background(), rec); err != nil {
+			t.Fatalf("%s: failed to add record: %s", net, err)
+		}
+	}
+
+	if got := ts.TXT("_acme-challenge.example.com."); !slices.Equal(got, []string{"udp", "tcp"}) {
+		t.Fatalf("want [udp tcp], got %v", got)
+	}
+}
+
+func TestSIG0UpdateUnknownKey(t *testing.T) {
+	public, private := newTestSIG0Key(t)
+	key, err := parseSIG0Key(public, private)
+	if err != nil {
+		t.Fatalf("failed to parse SIG(0) key: %s", err)
+	}
+
+	otherPublic, otherPrivate := newTestSIG0Key(t)
+	otherKey, err := parseSIG0Key(otherPublic, otherPrivate)
+	if err != nil {
+		t.Fatalf("failed to parse SIG(0) key: %s", err)
+	}
+
+	ts := newTestServer(t)
+	ts.AcceptSIG0(otherKey.Key)
+
+	client := newSIG0RFC2136Client(key)
+	client.nameservers = []string{ts.Addr}
+
+	rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token"}
+	if _, err := client.AddTXT(context.Background(), rec); err == nil {
+		t.Fatal("want error when signing with an unknown key")
+	}
+}
+
+func TestParseSIG0KeyInvalid(t *testing.T) {
+	public, private := newTestSIG0Key(t)
+
+	testCases := []struct {
+		public  []byte
+		private []byte
+	}{
+		{public: []byte("garbage"), private: private},
+		{public: []byte("example.com. 300 IN TXT foo"), private: private},
+		{public: public, private: []byte("garbage")},
+	}
+
+	for _, tc := range testCases {
+		if _, err := parseSIG0Key(tc.public, tc.private); !errors.Is(err, ErrInvalidSIG0Key) {
+			t.Errorf("want ErrInvalidSIG0Key, got %v", err)
+		}
+	}
+}
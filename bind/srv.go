@@ -0,0 +1,160 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// ErrUnknownDiscovery is returned when the solver was configured
+// with an unknown nameserver discovery mode.
+var ErrUnknownDiscovery = errors.New("unknown discovery")
+
+// ErrSRVDiscoveryTLS is returned when the nameservers were configured
+// to be discovered from SRV records with the tls transport.  The
+// _dns-update._tcp records point at the plain DNS port of the
+// servers, which does not accept DNS-over-TLS.
+var ErrSRVDiscoveryTLS = errors.New("srv discovery does not support the tls transport")
+
+const (
+	// DiscoverySOA discovers the nameservers of a zone from its
+	// SOA and NS records
+	DiscoverySOA = "soa"
+
+	// DiscoverySRV discovers the nameservers of a zone from the
+	// _dns-update._udp and _dns-update._tcp SRV records of the
+	// zone
+	DiscoverySRV = "srv"
+)
+
+// DefaultDiscovery is the nameserver discovery mode used, unless
+// specified in the configuration.
+const DefaultDiscovery = DiscoverySOA
+
+// srvServices returns the names of the SRV services of the update
+// servers, which accept updates over the transport, in order of
+// preference.
+func srvServices(network string) []string {
+	switch network {
+	case TransportUDP:
+		return []string{"_dns-update._udp"}
+	case TransportTCP:
+		return []string{"_dns-update._tcp"}
+	default:
+		return []string{"_dns-update._udp", "_dns-update._tcp"}
+	}
+}
+
+// lookupUpdateServers looks up the _dns-update SRV records of the
+// zone, which point at the servers accepting dynamic updates over the
+// transport, and returns the lowest TTL of the records as well.  When
+// the transport accepts both UDP and TCP, the _udp records are
+// preferred and the _tcp records are only used if there are none.
+func lookupUpdateServers(ctx context.Context, r *resolver, zone, network string) ([]*dns.SRV, time.Duration, error) {
+	for _, service := range srvServices(network) {
+		name := service + "." + dns.Fqdn(zone)
+		rrs, err := r.lookup(ctx, name, dns.TypeSRV)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to discover update servers of %s: %w", zone, err)
+		}
+
+		if len(rrs) == 0 {
+			continue
+		}
+
+		// A single record with the root as target means that
+		// the service is decidedly not available (RFC 2782)
+		records := make([]*dns.SRV, 0, len(rrs))
+		for _, rr := range rrs {
+			if srv := rr.(*dns.SRV); srv.Target != "." {
+				records = append(records, srv)
+			}
+		}
+
+		if len(records) == 0 {
+			return nil, 0, fmt.Errorf("%w for %s: %s is not available", ErrNoNameserverFound, zone, name)
+		}
+
+		return records, minTTL(rrs), nil
+	}
+
+	return nil, 0, fmt.Errorf("%w for %s: no _dns-update SRV records", ErrNoNameserverFound, zone)
+}
+
+// srvEndpoints returns the endpoints of the targets of the SRV
+// records, in the order they are tried, i.e. ordered by the priority
+// and weight of the records, as described in RFC 2782.
+func srvEndpoints(records []*dns.SRV) []endpoint {
+	endpoints := make([]endpoint, 0, len(records))
+	for _, srv := range orderSRV(records) {
+		host := strings.TrimSuffix(srv.Target, ".")
+		endpoints = append(endpoints, endpoint{Host: host, Port: strconv.Itoa(int(srv.Port))})
+	}
+
+	return endpoints
+}
+
+// orderSRV orders the SRV records by ascending priority, and the
+// records of the same priority in a random order, in which records
+// with a greater weight are more likely to come first, as described
+// in RFC 2782.
+func orderSRV(records []*dns.SRV) []*dns.SRV {
+	records = slices.Clone(records)
+	slices.SortStableFunc(records, func(a, b *dns.SRV) int {
+		return int(a.Priority) - int(b.Priority)
+	})
+
+	ordered := make([]*dns.SRV, 0, len(records))
+	for len(records) > 0 {
+		n := 1
+		for n < len(records) && records[n].Priority == records[0].Priority {
+			n++
+		}
+
+		group := records[:n]
+		for len(group) > 0 {
+			i := pickSRV(group)
+			ordered = append(ordered, group[i])
+			group = slices.Delete(group, i, i+1)
+		}
+
+		records = records[n:]
+	}
+
+	return ordered
+}
+
+// pickSRV picks one of the records of the same priority at random,
+// weighted by their weight.  Records with a weight of zero have a
+// small chance of being picked, unless all records have a weight of
+// zero.
+func pickSRV(records []*dns.SRV) int {
+	total := 0
+	for _, srv := range records {
+		total += int(srv.Weight)
+	}
+
+	// Records of weight zero are placed first, so that they are
+	// picked, when the random number is zero
+	slices.SortStableFunc(records, func(a, b *dns.SRV) int {
+		return min(int(a.Weight), 1) - min(int(b.Weight), 1)
+	})
+
+	pick := rand.Intn(total + 1)
+	sum := 0
+	for i, srv := range records {
+		sum += int(srv.Weight)
+		if sum >= pick {
+			return i
+		}
+	}
+
+	return len(records) - 1
+}
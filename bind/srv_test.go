@@ -0,0 +1,117 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestDiscoverUpdateServers(t *testing.T) {
+	testCases := []struct {
+		name    string
+		network string
+		records []string
+		want    []string
+		wantErr error
+	}{
+		{
+			name:    "priority",
+			network: TransportUDP,
+			records: []string{
+				"_dns-update._udp.example.com. 3600 IN SRV 20 0 53 ns2.example.com.",
+				"_dns-update._udp.example.com. 3600 IN SRV 10 0 5353 ns1.example.com.",
+				"_dns-update._tcp.example.com. 3600 IN SRV 0 0 53 tcp.example.com.",
+			},
+			want: []string{"ns1.example.com:5353", "ns2.example.com:53"},
+		},
+		{
+			name:    "tcp",
+			network: TransportTCP,
+			records: []string{
+				"_dns-update._udp.example.com. 3600 IN SRV 0 0 53 udp.example.com.",
+				"_dns-update._tcp.example.com. 3600 IN SRV 0 0 53 tcp.example.com.",
+			},
+			want: []string{"tcp.example.com:53"},
+		},
+		{
+			name:    "auto falls back to tcp",
+			network: TransportAuto,
+			records: []string{
+				"_dns-update._tcp.example.com. 3600 IN SRV 0 0 53 tcp.example.com.",
+			},
+			want: []string{"tcp.example.com:53"},
+		},
+		{
+			name:    "not available",
+			network: TransportUDP,
+			records: []string{
+				"_dns-update._udp.example.com. 3600 IN SRV 0 0 0 .",
+			},
+			wantErr: ErrNoNameserverFound,
+		},
+		{
+			name:    "no records",
+			network: TransportAuto,
+			records: []string{
+				"example.com. 3600 IN NS ns1.example.com.",
+			},
+			wantErr: ErrNoNameserverFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		ts := newTestServer(t)
+		ts.AddRecords(t, tc.records...)
+
+		r := &resolver{Servers: []string{ts.Addr}}
+		got, err := findNameservers(context.Background(), r, "example.com.", DefaultUpdatePort, DiscoverySRV, tc.network)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: want error %v, got %v", tc.name, tc.wantErr, err)
+			continue
+		}
+
+		var servers []string
+		for _, ep := range got {
+			servers = append(servers, ep.String())
+		}
+
+		if !slices.Equal(servers, tc.want) {
+			t.Errorf("%s: want %v, got %v", tc.name, tc.want, servers)
+		}
+	}
+}
+
+func TestOrderSRV(t *testing.T) {
+	records := []*dns.SRV{
+		{Priority: 20, Weight: 0, Target: "backup.example.com."},
+		{Priority: 10, Weight: 90, Target: "heavy.example.com."},
+		{Priority: 10, Weight: 10, Target: "light.example.com."},
+		{Priority: 10, Weight: 0, Target: "zero.example.com."},
+	}
+
+	first := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		ordered := orderSRV(records)
+		if len(ordered) != len(records) {
+			t.Fatalf("want %d records, got %d", len(records), len(ordered))
+		}
+
+		if last := ordered[len(ordered)-1].Target; last != "backup.example.com." {
+			t.Fatalf("want lowest priority record last, got %s", last)
+		}
+
+		first[ordered[0].Target]++
+	}
+
+	// The expected shares are 90%, 10% and about 1%
+	if first["heavy.example.com."] < 800 || first["light.example.com."] < 50 || first["light.example.com."] > 200 {
+		t.Errorf("want records picked by weight, got %v", first)
+	}
+
+	if records[0].Target != "backup.example.com." {
+		t.Errorf("want the records left unchanged")
+	}
+}
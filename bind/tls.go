@@ -0,0 +1,59 @@
+package bind
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrIncompleteTLSClientCertificate is returned when only one of
+// the TLS client certificate and key was configured.
+var ErrIncompleteTLSClientCertificate = errors.New("both TLS client certificate and key must be configured")
+
+// newTLSConfig creates the TLS configuration for the tls transport.
+// The server certificate is verified against the given PEM-encoded
+// CA bundle, or against the system roots, if the bundle is empty.
+// The client certificate and key are optional.
+func newTLSConfig(caBundle, clientCert, clientKey []byte, serverName string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+
+	if len(caBundle) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, errors.New("no valid certificates found in TLS CA bundle")
+		}
+		cfg.RootCAs = pool
+	}
+
+	if len(clientCert) > 0 || len(clientKey) > 0 {
+		if len(clientCert) == 0 || len(clientKey) == 0 {
+			return nil, ErrIncompleteTLSClientCertificate
+		}
+
+		cert, err := tls.X509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// tlsFingerprint returns the fingerprint of the PEM-encoded CA bundle,
+// client certificate and key of a TLS configuration.
+func tlsFingerprint(caBundle, clientCert, clientKey []byte) string {
+	h := sha256.New()
+	for _, data := range [][]byte{caBundle, clientCert, clientKey} {
+		fmt.Fprintf(h, "%d:", len(data))
+		h.Write(data)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
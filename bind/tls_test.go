@@ -0,0 +1,69 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTLSTransport(t *testing.T) {
+	ts, certPEM, keyPEM := newTestTLSServer(t)
+	otherCA, _ := newTestCertificate(t)
+
+	testCases := []struct {
+		name       string
+		caBundle   []byte
+		clientCert []byte
+		clientKey  []byte
+		serverName string
+		wantErr    bool
+	}{
+		{name: "valid", caBundle: certPEM, clientCert: certPEM, clientKey: keyPEM, serverName: "ns1.example.com"},
+		{name: "no client certificate", caBundle: certPEM, serverName: "ns1.example.com", wantErr: true},
+		{name: "untrusted server", caBundle: otherCA, clientCert: certPEM, clientKey: keyPEM, serverName: "ns1.example.com", wantErr: true},
+		{name: "wrong server name", caBundle: certPEM, clientCert: certPEM, clientKey: keyPEM, serverName: "ns2.example.com", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		key, err := parseTSIGKey([]byte(testKey))
+		if err != nil {
+			t.Fatalf("failed to parse key: %s", err)
+		}
+
+		tlsConfig, err := newTLSConfig(tc.caBundle, tc.clientCert, tc.clientKey, tc.serverName)
+		if err != nil {
+			t.Fatalf("%s: failed to create TLS config: %s", tc.name, err)
+		}
+
+		client := newRFC2136Client(key)
+		client.nameservers = []string{ts.Addr}
+		client.transport.Net = TransportTLS
+		client.transport.TLSConfig = tlsConfig
+
+		rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: tc.name}
+		_, err = client.AddTXT(context.Background(), rec)
+		if gotErr := err != nil; gotErr != tc.wantErr {
+			t.Errorf("%s: want error %t, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+
+	if got := ts.TXT("_acme-challenge.example.com."); len(got) != 1 || got[0] != "valid" {
+		t.Errorf("want only the valid record, got %v", got)
+	}
+}
+
+func TestNewTLSConfigInvalid(t *testing.T) {
+	certPEM, keyPEM := newTestCertificate(t)
+
+	if _, err := newTLSConfig([]byte("garbage"), nil, nil, ""); err == nil {
+		t.Error("want error for invalid CA bundle")
+	}
+
+	if _, err := newTLSConfig(nil, certPEM, nil, ""); !errors.Is(err, ErrIncompleteTLSClientCertificate) {
+		t.Errorf("want ErrIncompleteTLSClientCertificate, got %v", err)
+	}
+
+	if _, err := newTLSConfig(nil, keyPEM, certPEM, ""); err == nil {
+		t.Error("want error for mismatched certificate and key")
+	}
+}
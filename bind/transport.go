@@ -0,0 +1,394 @@
+package bind
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// ErrUnknownTransport is returned when the solver was configured
+// with an unsupported transport.
+var ErrUnknownTransport = errors.New("unknown transport")
+
+// ErrInvalidSourceAddress is returned when the source address is
+// not an IP address, optionally followed by a port.
+var ErrInvalidSourceAddress = errors.New("invalid source address")
+
+// Transports over which the dynamic updates are sent.
+const (
+	// TransportUDP sends the updates over UDP only
+	TransportUDP = "udp"
+
+	// TransportTCP sends the updates over TCP only
+	TransportTCP = "tcp"
+
+	// TransportAuto sends the updates over UDP and retries over
+	// TCP, if the response was truncated
+	TransportAuto = "auto"
+
+	// TransportTLS sends the updates over TLS (RFC 7858)
+	TransportTLS = "tls"
+)
+
+// DefaultTransport is the transport used, unless specified in the
+// configuration.
+const DefaultTransport = TransportAuto
+
+// Default timeouts used when talking to the nameservers, unless
+// specified in the configuration.
+const (
+	// DefaultDialTimeout is the default timeout for establishing
+	// a connection
+	DefaultDialTimeout = 5 * time.Second
+
+	// DefaultReadTimeout is the default timeout for reading a
+	// response
+	DefaultReadTimeout = 5 * time.Second
+
+	// DefaultWriteTimeout is the default timeout for sending a
+	// request
+	DefaultWriteTimeout = 5 * time.Second
+)
+
+// transport exchanges messages with a nameserver.
+type transport struct {
+	// Net is one of the supported transports
+	Net string
+
+	// DialTimeout is the timeout for establishing a connection
+	DialTimeout time.Duration
+
+	// ReadTimeout is the timeout for reading a response
+	ReadTimeout time.Duration
+
+	// WriteTimeout is the timeout for sending a request
+	WriteTimeout time.Duration
+
+	// TSIG is the TSIG key used to sign the requests and verify
+	// the responses
+	TSIG *tsigKey
+
+	// SIG0 is the SIG(0) key used to sign the requests, when
+	// not using TSIG
+	SIG0 *sig0Key
+
+	// TLSConfig is the TLS configuration used by the tls
+	// transport
+	TLSConfig *tls.Config
+
+	// SourceAddr is the local address the requests are sent
+	// from.  If the port is zero, an ephemeral port is used.
+	SourceAddr netip.AddrPort
+
+	// Resolver looks up the addresses of the nameservers.  If
+	// nil, the resolver of the system is used.
+	Resolver *resolver
+}
+
+// newTransport creates a new transport with the default settings.
+func newTransport() *transport {
+	t := &transport{
+		Net:          DefaultTransport,
+		DialTimeout:  DefaultDialTimeout,
+		ReadTimeout:  DefaultReadTimeout,
+		WriteTimeout: DefaultWriteTimeout,
+	}
+
+	return t
+}
+
+// client returns a DNS client for the given network.
+func (t *transport) client(network string) *dns.Client {
+	c := &dns.Client{
+		Net:          network,
+		DialTimeout:  t.DialTimeout,
+		ReadTimeout:  t.ReadTimeout,
+		WriteTimeout: t.WriteTimeout,
+		TLSConfig:    t.TLSConfig,
+	}
+
+	// Setting a nil *tsigKey as provider would result in a
+	// non-nil interface value.
+	if t.TSIG != nil {
+		c.TsigProvider = t.TSIG
+	}
+
+	if t.SourceAddr.IsValid() {
+		dialer := &net.Dialer{Timeout: t.DialTimeout}
+		if network == TransportUDP {
+			dialer.LocalAddr = net.UDPAddrFromAddrPort(t.SourceAddr)
+		} else {
+			dialer.LocalAddr = net.TCPAddrFromAddrPort(t.SourceAddr)
+		}
+		c.Dialer = dialer
+	}
+
+	return c
+}
+
+// sourcePorts serializes the exchanges sent from the same fixed
+// source port, since only one socket can be bound to it at a time.
+var sourcePorts = struct {
+	sync.Mutex
+	m map[netip.AddrPort]chan struct{}
+}{m: make(map[netip.AddrPort]chan struct{})}
+
+// lockSourcePort waits until no other exchange is sent from the
+// source address, or until the context is done.  The returned
+// function releases the source address.
+func lockSourcePort(ctx context.Context, addr netip.AddrPort) (func(), error) {
+	sourcePorts.Lock()
+	sem, ok := sourcePorts.m[addr]
+	if !ok {
+		sem = make(chan struct{}, 1)
+		sourcePorts.m[addr] = sem
+	}
+	sourcePorts.Unlock()
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// parseSourceAddress parses the source address, which is either an
+// IPv4 or IPv6 address, or an address followed by a port, e.g.
+// 192.0.2.1, 192.0.2.1:5300, 2001:db8::1 or [2001:db8::1]:5300.
+func parseSourceAddress(s string) (netip.AddrPort, error) {
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return netip.AddrPortFrom(addr, 0), nil
+	}
+
+	addrPort, err := netip.ParseAddrPort(s)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("%w: %s", ErrInvalidSourceAddress, s)
+	}
+
+	return addrPort, nil
+}
+
+// withoutPort returns the source address with an ephemeral port, for
+// messages, which may be sent over TCP, or to many servers at once.
+func withoutPort(addr netip.AddrPort) netip.AddrPort {
+	if !addr.IsValid() {
+		return addr
+	}
+
+	return netip.AddrPortFrom(addr.Addr(), 0)
+}
+
+// port returns the default port of the nameservers for the
+// transport.
+func (t *transport) port() string {
+	if t.Net == TransportTLS {
+		return DefaultTLSPort
+	}
+
+	return DefaultUpdatePort
+}
+
+// exchange sends the message to the nameserver and returns the
+// response.  When using the auto transport the message is re-sent
+// over TCP, if the UDP response was truncated, or sent over TCP right
+// away, if it does not fit into a UDP message.
+func (t *transport) exchange(ctx context.Context, msg *dns.Msg, server string) (*dns.Msg, error) {
+	switch t.Net {
+	case TransportUDP, TransportTCP:
+		return t.exchangeOver(ctx, t.Net, msg, server)
+	case TransportAuto:
+		// Large messages, e.g. batched updates, are sent over
+		// TCP right away.
+		if msg.Len() > dns.MinMsgSize {
+			return t.exchangeOver(ctx, TransportTCP, msg, server)
+		}
+		resp, err := t.exchangeOver(ctx, TransportUDP, msg, server)
+		if err != nil || !resp.Truncated {
+			return resp, err
+		}
+		return t.exchangeOver(ctx, TransportTCP, msg, server)
+	case TransportTLS:
+		return t.exchangeOver(ctx, "tcp-tls", msg, server)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTransport, t.Net)
+	}
+}
+
+// exchangeEndpoint sends the message to the nameserver at the given
+// endpoint and returns the response.  If the host of the endpoint
+// resolves to multiple addresses, the next address is tried in
+// parallel when no response was received within a short delay, as
+// described in RFC 8305.  The first response received wins.
+func (t *transport) exchangeEndpoint(ctx context.Context, msg *dns.Msg, ep endpoint) (*dns.Msg, error) {
+	addrs, err := resolveEndpoint(ctx, t.Resolver, ep)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the addresses of the same family as the source
+	// address are reachable.
+	if t.SourceAddr.IsValid() {
+		is4 := t.SourceAddr.Addr().Unmap().Is4()
+		addrs = slices.DeleteFunc(addrs, func(addr netip.AddrPort) bool {
+			return addr.Addr().Unmap().Is4() != is4
+		})
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("no address of %s matches the family of source address %s", ep, t.SourceAddr.Addr())
+		}
+	}
+
+	// Verify the certificate of the nameserver against its
+	// hostname, since we are connecting to its addresses.
+	tr := t
+	if t.TLSConfig != nil && t.TLSConfig.ServerName == "" {
+		if _, err := netip.ParseAddr(ep.Host); err != nil {
+			tr = new(transport)
+			*tr = *t
+			tr.TLSConfig = t.TLSConfig.Clone()
+			tr.TLSConfig.ServerName = ep.Host
+		}
+	}
+
+	return tr.exchangeAddrs(ctx, msg, addrs)
+}
+
+// exchangeAddrs sends the message to the first of the addresses, and
+// to the next one in parallel, if no response was received within a
+// short delay or the attempt failed.  Returns the first response
+// received.
+func (t *transport) exchangeAddrs(ctx context.Context, msg *dns.Msg, addrs []netip.AddrPort) (*dns.Msg, error) {
+	switch len(addrs) {
+	case 0:
+		return nil, ErrNoNameserverFound
+	case 1:
+		return t.exchange(ctx, msg, addrs[0].String())
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		resp *dns.Msg
+		err  error
+	}
+
+	results := make(chan result, len(addrs))
+	next, pending := 0, 0
+	attempt := func() {
+		addr := addrs[next].String()
+		next++
+		pending++
+		go func() {
+			resp, err := t.exchange(ctx, msg, addr)
+			results <- result{resp: resp, err: err}
+		}()
+	}
+
+	attempt()
+	timer := time.NewTimer(happyEyeballsDelay)
+	defer timer.Stop()
+
+	var errs []error
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.resp != nil {
+				return r.resp, r.err
+			}
+			errs = append(errs, r.err)
+			if next < len(addrs) {
+				attempt()
+				resetTimer(timer, happyEyeballsDelay)
+			}
+		case <-timer.C:
+			if next < len(addrs) {
+				attempt()
+				resetTimer(timer, happyEyeballsDelay)
+			}
+		}
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// resetTimer stops the timer and resets it to fire after the given
+// duration.  A tick, which fired but was not received, is drained
+// first, so that it is not mistaken for the next one.
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
+
+// exchangeOver sends the message to the nameserver over the given
+// network.  A copy of the message is sent, since signing a message
+// strips its TSIG record, which would prevent re-sending it.
+func (t *transport) exchangeOver(ctx context.Context, network string, msg *dns.Msg, server string) (*dns.Msg, error) {
+	if t.SourceAddr.Port() != 0 {
+		release, err := lockSourcePort(ctx, t.SourceAddr)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	client := t.client(network)
+	if t.SIG0 == nil {
+		resp, _, err := client.ExchangeContext(ctx, msg.Copy(), server)
+		return resp, err
+	}
+
+	// SIG(0) signed messages are sent in wire format, since the
+	// DNS client supports TSIG only.
+	buf, err := t.SIG0.sign(msg.Copy())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign message: %w", err)
+	}
+
+	conn, err := client.DialContext(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	now := time.Now()
+	writeDeadline := now.Add(t.WriteTimeout)
+	readDeadline := now.Add(t.ReadTimeout)
+	if deadline, ok := ctx.Deadline(); ok {
+		if deadline.Before(writeDeadline) {
+			writeDeadline = deadline
+		}
+		if deadline.Before(readDeadline) {
+			readDeadline = deadline
+		}
+	}
+	conn.SetWriteDeadline(writeDeadline)
+	conn.SetReadDeadline(readDeadline)
+
+	if _, err := conn.Write(buf); err != nil {
+		return nil, err
+	}
+
+	for {
+		resp, err := conn.ReadMsg()
+		// Ignore replies with mismatched IDs, which might be
+		// responses to earlier requests.
+		if err != nil || resp.Id == msg.Id {
+			return resp, err
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+)
+
+func TestTransportExchange(t *testing.T) {
+	testCases := []struct {
+		net         string
+		truncateUDP bool
+		wantUDP     int
+		wantTCP     int
+	}{
+		// Each record is queried, before sending the update
+		{net: TransportUDP, wantUDP: 2},
+		{net: TransportTCP, wantTCP: 2},
+		{net: TransportAuto, wantUDP: 2},
+		{net: TransportAuto, truncateUDP: true, wantUDP: 2, wantTCP: 2},
+	}
+
+	for _, tc := range testCases {
+		ts := newTestServer(t)
+		if tc.truncateUDP {
+			ts.TruncateUDP()
+		}
+
+		key, err := parseTSIGKey([]byte(testKey))
+		if err != nil {
+			t.Fatalf("failed to parse key: %s", err)
+		}
+
+		client := newRFC2136Client(key)
+		client.nameservers = []string{ts.Addr}
+		client.transport.Net = tc.net
+
+		rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token"}
+		if _, err := client.AddTXT(context.Background(), rec); err != nil {
+			t.Fatalf("%s: failed to add record: %s", tc.net, err)
+		}
+
+		if ts.Requests("udp") != tc.wantUDP || ts.Requests("tcp") != tc.wantTCP {
+			t.Errorf("%s: want %d udp and %d tcp requests, got %d and %d",
+				tc.net, tc.wantUDP, tc.wantTCP, ts.Requests("udp"), ts.Requests("tcp"))
+		}
+
+		if ts.Updates() != 1 {
+			t.Errorf("%s: want 1 update, got %d", tc.net, ts.Updates())
+		}
+	}
+}
+
+func TestTransportUnknown(t *testing.T) {
+	tr := newTransport()
+	tr.Net = "sctp"
+
+	_, err := tr.exchange(context.Background(), nil, "127.0.0.1:53")
+	if !errors.Is(err, ErrUnknownTransport) {
+		t.Fatalf("want ErrUnknownTransport, got %v", err)
+	}
+}
+
+func TestParseSourceAddress(t *testing.T) {
+	testCases := []struct {
+		addr    string
+		want    string
+		wantErr error
+	}{
+		{addr: "192.0.2.1", want: "192.0.2.1:0"},
+		{addr: "192.0.2.1:5300", want: "192.0.2.1:5300"},
+		{addr: "2001:db8::1", want: "[2001:db8::1]:0"},
+		{addr: "[2001:db8::1]:5300", want: "[2001:db8::1]:5300"},
+		{addr: "ns1.example.com", wantErr: ErrInvalidSourceAddress},
+		{addr: "192.0.2.1:65536", wantErr: ErrInvalidSourceAddress},
+		{addr: "2001:db8::1:5300:", wantErr: ErrInvalidSourceAddress},
+	}
+
+	for _, tc := range testCases {
+		got, err := parseSourceAddress(tc.addr)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: want error %v, got %v", tc.addr, tc.wantErr, err)
+			continue
+		}
+
+		if err == nil && got.String() != tc.want {
+			t.Errorf("%s: want %s, got %s", tc.addr, tc.want, got)
+		}
+	}
+}
+
+func TestTransportSourceAddress(t *testing.T) {
+	// Find a free UDP port to send the updates from
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	source := pc.LocalAddr().String()
+	pc.Close()
+
+	testCases := []struct {
+		net    string
+		source string
+	}{
+		{net: TransportUDP, source: source},
+		{net: TransportTCP, source: "127.0.0.1"},
+	}
+
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	for _, tc := range testCases {
+		ts := newTestServer(t)
+		client := newRFC2136Client(key)
+		client.nameservers = []string{ts.Addr}
+		client.transport.Net = tc.net
+		if client.transport.SourceAddr, err = parseSourceAddress(tc.source); err != nil {
+			t.Fatalf("failed to parse source address: %s", err)
+		}
+
+		rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: "token"}
+		if _, err := client.AddTXT(context.Background(), rec); err != nil {
+			t.Fatalf("%s: failed to add record: %s", tc.net, err)
+		}
+
+		remote := ts.RemoteAddrs()
+		if len(remote) != 2 {
+			t.Fatalf("%s: want a query and an update, got %v", tc.net, remote)
+		}
+
+		for _, addr := range remote {
+			got := netip.MustParseAddrPort(addr)
+			if got.Addr() != client.transport.SourceAddr.Addr() {
+				t.Errorf("%s: want request from %s, got %s", tc.net, tc.source, got)
+			}
+
+			if port := client.transport.SourceAddr.Port(); port != 0 && got.Port() != port {
+				t.Errorf("%s: want request from port %d, got %d", tc.net, port, got.Port())
+			}
+		}
+	}
+}
+
+func TestTransportSourcePortConcurrent(t *testing.T) {
+	// Find a free UDP port to send the updates from
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	source := pc.LocalAddr().String()
+	pc.Close()
+
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	ts := newTestServer(t)
+	client := newRFC2136Client(key)
+	client.nameservers = []string{ts.Addr}
+	client.transport.Net = TransportUDP
+	if client.transport.SourceAddr, err = parseSourceAddress(source); err != nil {
+		t.Fatalf("failed to parse source address: %s", err)
+	}
+
+	// The updates sent at once from the same port must not fail
+	// to bind it
+	errs := make([]error, 8)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			rec := ChallengeRecord{Zone: "example.com.", FQDN: fmt.Sprintf("_acme-challenge.host%d.example.com.", i), TTL: 300, Value: "token"}
+			_, errs[i] = client.AddTXT(context.Background(), rec)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("update %d: %s", i, err)
+		}
+	}
+
+	for _, addr := range ts.RemoteAddrs() {
+		if got := netip.MustParseAddrPort(addr); got != client.transport.SourceAddr {
+			t.Errorf("want request from %s, got %s", source, got)
+		}
+	}
+}
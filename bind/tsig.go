@@ -0,0 +1,251 @@
+package bind
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/miekg/dns"
+)
+
+// ErrInvalidTSIGKey is returned when the TSIG key could not be
+// parsed.
+var ErrInvalidTSIGKey = errors.New("invalid TSIG key")
+
+// ErrUnsupportedTSIGAlgorithm is returned when the TSIG key uses an
+// algorithm, which is not supported.
+var ErrUnsupportedTSIGAlgorithm = errors.New("unsupported TSIG algorithm")
+
+// ErrLegacyTSIGAlgorithm is returned when the TSIG key uses
+// hmac-md5, without explicitly allowing it.
+var ErrLegacyTSIGAlgorithm = errors.New("hmac-md5 TSIG algorithm must be explicitly allowed")
+
+// ErrTSIGAlgorithmMismatch is returned when the configured TSIG
+// algorithm does not match the algorithm of the TSIG key.
+var ErrTSIGAlgorithmMismatch = errors.New("TSIG algorithm does not match the algorithm of the key")
+
+// ErrInvalidTSIGFudge is returned when the configured TSIG fudge is
+// out of range.
+var ErrInvalidTSIGFudge = errors.New("TSIG fudge must be between 1s and 65535s")
+
+// tsigAlgorithms maps the supported TSIG algorithms in canonical
+// form to their hash functions.
+var tsigAlgorithms = map[string]func() hash.Hash{
+	dns.HmacSHA1:   sha1.New,
+	dns.HmacSHA224: sha256.New224,
+	dns.HmacSHA256: sha256.New,
+	dns.HmacSHA384: sha512.New384,
+	dns.HmacSHA512: sha512.New,
+	dns.HmacMD5:    md5.New,
+}
+
+// canonicalTSIGAlgorithm validates the TSIG algorithm and returns it
+// in canonical form.  The legacy hmac-md5 algorithm is supported,
+// only if explicitly allowed.
+func canonicalTSIGAlgorithm(name string, allowMD5 bool) (string, error) {
+	algorithm := dns.CanonicalName(name)
+	if algorithm == "hmac-md5." {
+		algorithm = dns.HmacMD5
+	}
+
+	if _, ok := tsigAlgorithms[algorithm]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedTSIGAlgorithm, name)
+	}
+
+	if algorithm == dns.HmacMD5 && !allowMD5 {
+		return "", ErrLegacyTSIGAlgorithm
+	}
+
+	return algorithm, nil
+}
+
+// tsigKey represents a TSIG key, as generated by tsig-keygen(8).
+type tsigKey struct {
+	// Name is the name of the key in canonical form.
+	Name string
+
+	// Algorithm is the HMAC algorithm of the key in canonical
+	// form, e.g. hmac-sha256.
+	Algorithm string
+
+	// Secret is the base64 encoded shared secret.
+	Secret string
+
+	// Fudge is the time window in seconds, within which a
+	// signature is considered valid.
+	Fudge uint16
+}
+
+// Generate implements the dns.TsigProvider interface
+func (k *tsigKey) Generate(msg []byte, t *dns.TSIG) ([]byte, error) {
+	newHash, ok := tsigAlgorithms[dns.CanonicalName(t.Algorithm)]
+	if !ok {
+		return nil, dns.ErrKeyAlg
+	}
+
+	secret, err := base64.StdEncoding.DecodeString(k.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	h := hmac.New(newHash, secret)
+	h.Write(msg)
+
+	return h.Sum(nil), nil
+}
+
+// Verify implements the dns.TsigProvider interface
+func (k *tsigKey) Verify(msg []byte, t *dns.TSIG) error {
+	mac, err := k.Generate(msg, t)
+	if err != nil {
+		return err
+	}
+
+	expected, err := hex.DecodeString(t.MAC)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal(mac, expected) {
+		return dns.ErrSig
+	}
+
+	return nil
+}
+
+// tsigServerTime returns the current time of the server, as
+// reported in the TSIG record of a BADTIME response to a request
+// signed at the given time.  The server includes its time as a 48-bit
+// value in the Other Data field, as described in RFC 8945, section
+// 5.2.3.  Older servers sign the response with their own time
+// instead.  Returns false, if the response does not reveal the time
+// of the server.
+func tsigServerTime(t *dns.TSIG, requestTime uint64) (time.Time, bool) {
+	if t.OtherLen == 6 {
+		if secs, err := strconv.ParseUint(t.OtherData, 16, 64); err == nil {
+			return time.Unix(int64(secs), 0), true
+		}
+	}
+
+	if t.TimeSigned != 0 && t.TimeSigned != requestTime {
+		return time.Unix(int64(t.TimeSigned), 0), true
+	}
+
+	return time.Time{}, false
+}
+
+// parseTSIGKey parses a TSIG key in the named.conf(5) format, as
+// generated by tsig-keygen(8), e.g.
+//
+//	key "acme-key" {
+//		algorithm hmac-sha256;
+//		secret "base64-encoded-secret";
+//	};
+func parseTSIGKey(data []byte) (*tsigKey, error) {
+	tokens := tokenizeNamedConf(string(data))
+
+	// We expect the following sequence of tokens:
+	// key <name> { algorithm <alg> ; secret <secret> ; } ;
+	if len(tokens) < 3 || tokens[0] != "key" || tokens[2] != "{" {
+		return nil, fmt.Errorf("%w: missing key statement", ErrInvalidTSIGKey)
+	}
+
+	key := &tsigKey{
+		Name:  dns.CanonicalName(tokens[1]),
+		Fudge: DefaultTSIGFudge,
+	}
+
+	rest := tokens[3:]
+	for len(rest) > 0 && rest[0] != "}" {
+		if len(rest) < 3 || rest[2] != ";" {
+			return nil, fmt.Errorf("%w: malformed clause %q", ErrInvalidTSIGKey, rest[0])
+		}
+
+		switch rest[0] {
+		case "algorithm":
+			key.Algorithm = dns.CanonicalName(rest[1])
+		case "secret":
+			key.Secret = rest[1]
+		default:
+			return nil, fmt.Errorf("%w: unknown clause %q", ErrInvalidTSIGKey, rest[0])
+		}
+		rest = rest[3:]
+	}
+
+	if len(rest) == 0 {
+		return nil, fmt.Errorf("%w: unterminated key statement", ErrInvalidTSIGKey)
+	}
+
+	if key.Algorithm == "" {
+		return nil, fmt.Errorf("%w: no algorithm specified for key %s", ErrInvalidTSIGKey, key.Name)
+	}
+
+	if key.Secret == "" {
+		return nil, fmt.Errorf("%w: no secret specified for key %s", ErrInvalidTSIGKey, key.Name)
+	}
+
+	return key, nil
+}
+
+// tokenizeNamedConf splits the given named.conf(5) snippet into
+// tokens, stripping comments and the quotes around strings.
+func tokenizeNamedConf(s string) []string {
+	tokens := make([]string, 0)
+	var current strings.Builder
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '#' || (c == '/' && i+1 < len(s) && s[i+1] == '/'):
+			// Line comment
+			flush()
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			// Block comment
+			flush()
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 3
+		case c == '"':
+			flush()
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				end = len(s) - i - 1
+			}
+			tokens = append(tokens, s[i+1:i+1+end])
+			i += end + 1
+		case c == '{' || c == '}' || c == ';':
+			flush()
+			tokens = append(tokens, string(c))
+		case unicode.IsSpace(rune(c)):
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+
+	return tokens
+}
@@ -0,0 +1,146 @@
+package bind
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseTSIGKey(t *testing.T) {
+	key, err := parseTSIGKey([]byte(testKey))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	if key.Name != "acme-key." {
+		t.Errorf("want key name acme-key., got %s", key.Name)
+	}
+
+	if key.Algorithm != "hmac-sha256." {
+		t.Errorf("want algorithm hmac-sha256., got %s", key.Algorithm)
+	}
+
+	if key.Secret != "e6wQB/TVQ8ka38vh6CyGjUTnLH4EkUJhsLaiO0JgbPU=" {
+		t.Errorf("unexpected secret %s", key.Secret)
+	}
+}
+
+func TestParseTSIGKeyWithComments(t *testing.T) {
+	data := `# Generated by tsig-keygen
+key "Example-Key" { // the key
+	/* the algorithm */ algorithm HMAC-SHA512;
+	secret "c2VjcmV0";
+};`
+
+	key, err := parseTSIGKey([]byte(data))
+	if err != nil {
+		t.Fatalf("failed to parse key: %s", err)
+	}
+
+	if key.Name != "example-key." || key.Algorithm != "hmac-sha512." || key.Secret != "c2VjcmV0" {
+		t.Errorf("unexpected key %+v", key)
+	}
+}
+
+func TestParseTSIGKeyInvalid(t *testing.T) {
+	testCases := []string{
+		"",
+		`key "foo";`,
+		`key "foo" { algorithm hmac-sha256; };`,
+		`key "foo" { secret "c2VjcmV0"; };`,
+		`key "foo" { algorithm hmac-sha256; secret "c2VjcmV0";`,
+		`key "foo" { algorithm hmac-sha256; secret "c2VjcmV0"; unknown foo; };`,
+	}
+
+	for _, tc := range testCases {
+		if _, err := parseTSIGKey([]byte(tc)); !errors.Is(err, ErrInvalidTSIGKey) {
+			t.Errorf("want ErrInvalidTSIGKey for %q, got %v", tc, err)
+		}
+	}
+}
+
+func TestCanonicalTSIGAlgorithm(t *testing.T) {
+	testCases := []struct {
+		name     string
+		allowMD5 bool
+		want     string
+		wantErr  error
+	}{
+		{name: "hmac-sha1", want: "hmac-sha1."},
+		{name: "hmac-sha224", want: "hmac-sha224."},
+		{name: "HMAC-SHA256", want: "hmac-sha256."},
+		{name: "hmac-sha384.", want: "hmac-sha384."},
+		{name: "hmac-sha512", want: "hmac-sha512."},
+		{name: "hmac-md5", wantErr: ErrLegacyTSIGAlgorithm},
+		{name: "hmac-md5", allowMD5: true, want: "hmac-md5.sig-alg.reg.int."},
+		{name: "hmac-md5.sig-alg.reg.int", allowMD5: true, want: "hmac-md5.sig-alg.reg.int."},
+		{name: "hmac-sha3-256", wantErr: ErrUnsupportedTSIGAlgorithm},
+		{name: "gss-tsig", wantErr: ErrUnsupportedTSIGAlgorithm},
+	}
+
+	for _, tc := range testCases {
+		got, err := canonicalTSIGAlgorithm(tc.name, tc.allowMD5)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: want error %v, got %v", tc.name, tc.wantErr, err)
+		}
+
+		if got != tc.want {
+			t.Errorf("%s: want %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestTSIGAlgorithms(t *testing.T) {
+	ts := newTestServer(t)
+	algorithms := []string{dns.HmacSHA1, dns.HmacSHA224, dns.HmacSHA256, dns.HmacSHA384, dns.HmacSHA512}
+
+	for _, algorithm := range algorithms {
+		key, err := parseTSIGKey([]byte(testKey))
+		if err != nil {
+			t.Fatalf("failed to parse key: %s", err)
+		}
+		key.Algorithm = algorithm
+
+		client := newRFC2136Client(key)
+		client.nameservers = []string{ts.Addr}
+
+		rec := ChallengeRecord{Zone: "example.com.", FQDN: "_acme-challenge.example.com.", TTL: 300, Value: algorithm}
+		if _, err := client.AddTXT(context.Background(), rec); err != nil {
+			t.Errorf("%s: failed to add record: %s", algorithm, err)
+		}
+	}
+
+	if got := ts.TXT("_acme-challenge.example.com."); len(got) != len(algorithms) {
+		t.Errorf("want %d records, got %v", len(algorithms), got)
+	}
+}
+
+func TestTSIGKeyHMACMD5(t *testing.T) {
+	// Test case 1 from RFC 2104
+	key := &tsigKey{
+		Name:      "md5-key.",
+		Algorithm: dns.HmacMD5,
+		Secret:    base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0x0b}, 16)),
+	}
+
+	tsig := &dns.TSIG{Algorithm: dns.HmacMD5}
+	mac, err := key.Generate([]byte("Hi There"), tsig)
+	if err != nil {
+		t.Fatalf("failed to generate MAC: %s", err)
+	}
+
+	want := "9294727a3638bb1c13f48ef8158bfc9d"
+	if got := hex.EncodeToString(mac); got != want {
+		t.Fatalf("want MAC %s, got %s", want, got)
+	}
+
+	tsig.MAC = want
+	if err := key.Verify([]byte("Hi There"), tsig); err != nil {
+		t.Fatalf("failed to verify MAC: %s", err)
+	}
+}
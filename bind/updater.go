@@ -0,0 +1,79 @@
+package bind
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnknownBackend is returned when the solver was configured
+// with a backend, which has not been registered.
+var ErrUnknownBackend = errors.New("unknown backend")
+
+// Names of the builtin backends.
+const (
+	// BackendNative sends the dynamic updates natively
+	BackendNative = "native"
+
+	// BackendScript calls the ACME helper script
+	BackendScript = "script"
+
+	// BackendMemory keeps the records in memory
+	BackendMemory = "memory"
+)
+
+// DefaultBackend is the backend used, unless specified in the
+// configuration.
+const DefaultBackend = BackendNative
+
+// ChallengeRecord represents the TXT record of an ACME DNS-01
+// challenge.
+type ChallengeRecord struct {
+	// Zone is the zone in which the record is managed
+	Zone string
+
+	// FQDN is the fully qualified name of the record
+	FQDN string
+
+	// TTL is the time-to-live of the record
+	TTL int
+
+	// Value is the value of the TXT record, i.e. the challenge
+	// key
+	Value string
+
+	// UID is the unique identifier of the challenge
+	UID string
+
+	// DNSName is the name of the domain, which is being
+	// validated
+	DNSName string
+
+	// ResourceNamespace is the namespace of the issuer
+	ResourceNamespace string
+}
+
+// Result describes the outcome of adding or removing a TXT record.
+type Result struct {
+	// Changed is false, if the record was already in the desired
+	// state, i.e. it already existed when adding it, or did not
+	// exist when removing it.
+	Changed bool
+
+	// Server is the nameserver, which applied the change or
+	// reported the state of the record, if known
+	Server string
+}
+
+// Updater is the interface implemented by backends, which add and
+// remove the ACME challenge TXT records.  Both methods must be
+// idempotent.
+type Updater interface {
+	// AddTXT creates the given TXT record
+	AddTXT(ctx context.Context, rec ChallengeRecord) (Result, error)
+
+	// RemoveTXT deletes the given TXT record
+	RemoveTXT(ctx context.Context, rec ChallengeRecord) (Result, error)
+}
+
+// BackendFactory creates a new Updater for the given configuration.
+type BackendFactory func(cfg *BindProviderConfig) (Updater, error)
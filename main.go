@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/cert-manager/cert-manager/pkg/acme/webhook/cmd"
 	"github.com/dnaeon/cert-manager-webhook-bind9/bind"
@@ -17,5 +19,34 @@ func main() {
 	}
 
 	solver := bind.NewSolver()
+	if backend := os.Getenv("DEFAULT_BACKEND"); backend != "" {
+		solver.Backend = backend
+	}
+
+	if addr := os.Getenv("SOURCE_ADDRESS"); addr != "" {
+		solver.SourceAddress = addr
+	}
+
+	if limit := os.Getenv("MAX_CONCURRENT_UPDATES"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid MAX_CONCURRENT_UPDATES: %s\n", err)
+			os.Exit(1)
+		}
+		solver.MaxConcurrentUpdates = n
+	}
+
+	if resolvers := os.Getenv("RESOLVERS"); resolvers != "" {
+		for _, r := range strings.Split(resolvers, ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				solver.Resolvers = append(solver.Resolvers, r)
+			}
+		}
+	}
+
+	if url := os.Getenv("DOH_URL"); url != "" {
+		solver.DoHURL = url
+	}
+
 	cmd.RunWebhookServer(GroupName, solver)
 }
@@ -14,12 +14,10 @@ func TestRunsSuite(t *testing.T) {
 	// ChallengeRequest passed as part of the test cases.
 	//
 
-	// Adjust the path to the ACME helper script during testing.
-	// Also, configure the ACME helper script to use the test
-	// nameserver during the conformance tests.
+	// Configure the solver to use the test nameserver during the
+	// conformance tests.
 	os.Setenv("USE_NAMESERVER", "172.16.0.3")
 	solver := bind.NewSolver()
-	solver.AcmeHelperScript = "./scripts/acme-challenge-helper.sh"
 	fixture := dns.NewFixture(solver,
 		dns.SetResolvedZone("example.com."),
 		dns.SetAllowAmbientCredentials(false),
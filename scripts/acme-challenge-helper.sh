@@ -3,6 +3,31 @@
 # A helper script which uses nsupdate(1) to handle ACME DNS-01
 # challenges
 #
+# The script implements version 1 of the hook protocol. The request
+# is read as JSON from standard input and the result is written as
+# JSON on standard output, e.g.
+#
+# Request:
+#
+#   {
+#     "version": 1,
+#     "operation": "present",
+#     "uid": "...",
+#     "dnsName": "example.com",
+#     "resourceNamespace": "cert-manager",
+#     "zone": "example.com.",
+#     "fqdn": "_acme-challenge.example.com.",
+#     "ttl": 300,
+#     "key": "...",
+#     "tsigKeyFile": "/tmp/tsig-key123"
+#   }
+#
+# Result:
+#
+#   {"version": 1, "success": true}
+#
+# Diagnostic messages are written on standard error.
+#
 
 set -e
 
@@ -13,23 +38,70 @@ TMPDIR=${TMPDIR:-"/tmp"}
 # querying the zone for the NS records.
 USE_NAMESERVER=${USE_NAMESERVER:-}
 
+# The version of the hook protocol we implement
+_PROTOCOL_VERSION=1
+
 _SCRIPT_NAME="${0##*/}"
 
 # Prints the usage of the sript
 function _usage() {
-    echo "${_SCRIPT_NAME} [create|delete] <zone> <fqdn> <tsig-key> <ttl> <token>"
+    echo "${_SCRIPT_NAME} < request.json" >&2
     exit 64  # EX_USAGE
 }
 
+# Writes the result of the operation on standard output
+#
+# $1: Whether the operation succeeded (true or false)
+# $2: Optional message
+function _result() {
+    jq -n -c \
+       --argjson version "${_PROTOCOL_VERSION}" \
+       --argjson success "${1}" \
+       --arg message "${2}" \
+       '{version: $version, success: $success, message: $message}'
+}
+
+# Splits the nameserver endpoint, i.e. host, host:port or [v6]:port,
+# into the host and port arguments of the nsupdate(1) server command
+#
+# $1: Nameserver endpoint
+function _server_args() {
+    local _endpoint="${1}"
+    local _host=""
+
+    case "${_endpoint}" in
+	\[*\]:*)
+	    _host="${_endpoint%%]:*}"
+	    echo "${_host#[} ${_endpoint##*]:}"
+	    ;;
+	\[*\])
+	    _host="${_endpoint#[}"
+	    echo "${_host%]}"
+	    ;;
+	*:*:*)
+	    # A bare IPv6 address
+	    echo "${_endpoint}"
+	    ;;
+	*:*)
+	    echo "${_endpoint%:*} ${_endpoint##*:}"
+	    ;;
+	*)
+	    echo "${_endpoint}"
+	    ;;
+    esac
+}
+
 # Handles the ACME challenge by either creating or deleting the
 # respective DNS TXT record
 #
-# $1: Operation (either create or delete)
+# $1: Operation (either present or cleanup)
 # $2: Zone name
 # $3: FQDN
 # $4: Path to TSIG key
 # $5: TTL
 # $6: Token / Key
+# $7: Space-separated nameservers from the request, tried in order (optional)
+# $8: Space-separated recursive resolvers from the request (optional)
 function _handle_acme_challenge() {
     local _op="${1}"
     local _zone_name="${2}"
@@ -37,68 +109,107 @@ function _handle_acme_challenge() {
     local _tsig_key="${4}"
     local _ttl="${5}"
     local _token="${6}"
+    local _request_nameservers="${7}"
+    local _request_resolvers="${8}"
 
     # The operation we are about to perform
     local _operation=""
-    local _op_add="update add ${_fqdn} ${_ttl} TXT ${_token}"
-    local _op_delete="update delete ${_fqdn} TXT ${_token}"
+    local _op_add="update add ${_fqdn} ${_ttl} TXT \"${_token}\""
+    local _op_delete="update delete ${_fqdn} TXT \"${_token}\""
     case "${_op}" in
-	create)
+	present)
 	    _operation="${_op_add}"
 	    ;;
-	delete)
+	cleanup)
 	    _operation="${_op_delete}"
 	    ;;
 	*)
-	    _usage
+	    _result false "unknown operation ${_op}"
+	    exit 64  # EX_USAGE
 	    ;;
     esac
 
+    local _nameservers=""
     local _nameserver=""
-    local _script=$( mktemp nsupdate-script.XXXXXX )
-
-    # If $USE_NAMESERVER is specified forward queries to this
-    # nameserver, otherwise run the queries against the authoritative
-    # nameservers.
-    if [ ! -z "${USE_NAMESERVER}" ]; then
-	_nameserver="${USE_NAMESERVER}"
+    local _resolver=""
+    local _dig_args=""
+    local _script=$( mktemp "${TMPDIR}/nsupdate-script.XXXXXX" )
+
+    # Use the nameservers from the request, which the webhook
+    # either took from the issuer configuration, or discovered from
+    # the SOA and NS records of the zone.  If $USE_NAMESERVER is
+    # specified forward queries to this nameserver, otherwise run the
+    # queries against the authoritative nameservers.
+    if [ ! -z "${_request_nameservers}" ]; then
+	_nameservers="${_request_nameservers}"
+    elif [ ! -z "${USE_NAMESERVER}" ]; then
+	_nameservers="${USE_NAMESERVER}"
     else
+	# Query the first of the configured resolvers, if any
+	for _resolver in ${_request_resolvers}; do
+	    set -- $( _server_args "${_resolver}" )
+	    _dig_args="@${1}${2:+ -p ${2}}"
+	    break
+	done
+
 	# Use the first authoritative DNS servers for the zone
-	local _nameserver=$( dig +short -t ns "${_zone_name}" | head -1 )
+	_nameservers=$( dig ${_dig_args} +short -t ns "${_zone_name}" | head -1 )
     fi
 
     # We should have a nameserver in all cases
-    if [ -z "${_nameserver}" ]; then
-	echo "Unable to find authoritative DNS servers for ${_zone_name}"
+    if [ -z "${_nameservers}" ]; then
+	_result false "Unable to find authoritative DNS servers for ${_zone_name}"
 	exit 1
     fi
 
-    cat > "${_script}" <<__EOF__
+    # Try the nameservers in order, until one accepts the update
+    for _nameserver in ${_nameservers}; do
+	cat > "${_script}" <<__EOF__
 debug yes
-server ${_nameserver}
+server $( _server_args "${_nameserver}" )
 zone ${_zone_name}
 ${_operation}
 send
 __EOF__
 
-    nsupdate -k "${_tsig_key}" -v "${_script}"
+	# nsupdate(1) writes its debug output on standard error and
+	# anything else is diagnostics as well, so keep standard
+	# output for the result only.
+	if nsupdate -k "${_tsig_key}" -v "${_script}" 1>&2; then
+	    rm -f "${_script}"
+	    _result true ""
+	    return
+	fi
+    done
+
     rm -f "${_script}"
+    _result false "nsupdate failed for ${_fqdn}"
+    exit 1
 }
 
 # Main entrypoint
 function _main() {
-    local _cmd="${1}"
-    local _zone="${2}"
-    local _fqdn="${3}"
-    local _tsig_key="${4}"
-    local _ttl="${5}"
-    local _token="${6}"
-
-    if [ $# -ne 6 ]; then
+    if [ $# -ne 0 ]; then
 	_usage
     fi
 
-    _handle_acme_challenge "${_cmd}" "${_zone}" "${_fqdn}" "${_tsig_key}" "${_ttl}" "${_token}"
+    local _request=$( cat )
+    local _version=$( jq -r '.version' <<< "${_request}" )
+    if [ "${_version}" != "${_PROTOCOL_VERSION}" ]; then
+	_result false "unsupported protocol version ${_version}"
+	exit 64  # EX_USAGE
+    fi
+
+    local _cmd=$( jq -r '.operation' <<< "${_request}" )
+    local _zone=$( jq -r '.zone' <<< "${_request}" )
+    local _fqdn=$( jq -r '.fqdn' <<< "${_request}" )
+    local _tsig_key=$( jq -r '.tsigKeyFile' <<< "${_request}" )
+    local _ttl=$( jq -r '.ttl' <<< "${_request}" )
+    local _token=$( jq -r '.key' <<< "${_request}" )
+    local _nameservers=$( jq -r '.nameservers // [] | join(" ")' <<< "${_request}" )
+    local _resolvers=$( jq -r '.resolvers // [] | join(" ")' <<< "${_request}" )
+
+    _handle_acme_challenge "${_cmd}" "${_zone}" "${_fqdn}" "${_tsig_key}" "${_ttl}" "${_token}" "${_nameservers}" "${_resolvers}"
 }
 
-_main $*
+_main "$@"